@@ -0,0 +1,226 @@
+package dryrun
+
+import (
+	"context"
+
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ignoredMetadata are the metadata fields that are ignored in the diffs
+// because they're set by the API server.
+var ignoredMetadata = []string{"managedFields", "resourceVersion", "generation", "creationTimestamp", "uid"}
+
+// Client is a k8s client that performs the write operations with
+// client.DryRunAll and records the changes that would have been made. The
+// read operations are passed through.
+type Client struct {
+	client.Client
+
+	recorder *Recorder
+}
+
+var _ client.Client = &Client{}
+
+// NewClient returns a dry-run Client wrapping the given client and recording
+// the changes in the given Recorder.
+func NewClient(c client.Client, r *Recorder) *Client {
+	return &Client{Client: c, recorder: r}
+}
+
+// ClientFor returns a dry-run Client wrapping the given client if the context
+// is in dry-run mode, else the given client.
+func ClientFor(ctx context.Context, c client.Client) client.Client {
+	if r := RecorderFrom(ctx); r != nil {
+		return NewClient(c, r)
+	}
+	return c
+}
+
+// Create implements client.Client.
+func (c *Client) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	c.record(ActionCreate, "", nil, obj)
+	return nil
+}
+
+// Update implements client.Client.
+func (c *Client) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	live, err := c.live(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	c.record(ActionUpdate, "", live, obj)
+	return nil
+}
+
+// Patch implements client.Client.
+func (c *Client) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	live, err := c.live(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	c.record(ActionPatch, "", live, obj)
+	return nil
+}
+
+// Delete implements client.Client.
+func (c *Client) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	c.recorder.Record(Change{
+		Action:           ActionDelete,
+		GroupVersionKind: c.gvk(obj),
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+	})
+	return nil
+}
+
+// DeleteAllOf implements client.Client.
+func (c *Client) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	if err := c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	listOpts := &client.DeleteAllOfOptions{}
+	listOpts.ApplyOptions(opts)
+	c.recorder.Record(Change{
+		Action:           ActionDeleteAllOf,
+		GroupVersionKind: c.gvk(obj),
+		Namespace:        listOpts.Namespace,
+	})
+	return nil
+}
+
+// Status implements client.Client.
+func (c *Client) Status() client.SubResourceWriter {
+	return c.SubResource("status")
+}
+
+// SubResource implements client.Client.
+func (c *Client) SubResource(subResource string) client.SubResourceClient {
+	return &subResourceClient{
+		SubResourceClient: c.Client.SubResource(subResource),
+		client:            c,
+		subResource:       subResource,
+	}
+}
+
+// live returns a copy of the live object of the given object. It returns nil
+// if the object doesn't exist.
+func (c *Client) live(ctx context.Context, obj client.Object) (client.Object, error) {
+	live, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return live, nil
+}
+
+// gvk returns the GVK of the given object.
+func (c *Client) gvk(obj client.Object) schema.GroupVersionKind {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return obj.GetObjectKind().GroupVersionKind()
+	}
+	return gvk
+}
+
+// record records a change of the given object with the diff from the live
+// object.
+func (c *Client) record(action Action, subResource string, live, obj client.Object) {
+	c.recorder.Record(Change{
+		Action:           action,
+		GroupVersionKind: c.gvk(obj),
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+		Subresource:      subResource,
+		Diff:             Diff(live, obj),
+	})
+}
+
+// Diff returns the diff between two objects, ignoring the metadata set by
+// the API server. A nil object is compared as an empty object.
+func Diff(from, to client.Object) string {
+	return cmp.Diff(content(from), content(to))
+}
+
+// content returns the unstructured content of an object without the ignored
+// metadata.
+func content(obj client.Object) map[string]interface{} {
+	if obj == nil {
+		return map[string]interface{}{}
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	m = runtime.DeepCopyJSON(m)
+	for _, field := range ignoredMetadata {
+		unstructured.RemoveNestedField(m, "metadata", field)
+	}
+	return m
+}
+
+// subResourceClient is a SubResourceClient that performs the write
+// operations with client.DryRunAll and records the changes.
+type subResourceClient struct {
+	client.SubResourceClient
+
+	client      *Client
+	subResource string
+}
+
+// Create implements client.SubResourceWriter.
+func (s *subResourceClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	if err := s.SubResourceClient.Create(ctx, obj, subResource, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	s.client.record(ActionCreate, s.subResource, nil, subResource)
+	return nil
+}
+
+// Update implements client.SubResourceWriter.
+func (s *subResourceClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	live, err := s.client.live(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if err := s.SubResourceClient.Update(ctx, obj, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	s.client.record(ActionUpdate, s.subResource, live, obj)
+	return nil
+}
+
+// Patch implements client.SubResourceWriter.
+func (s *subResourceClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	live, err := s.client.live(ctx, obj)
+	if err != nil {
+		return err
+	}
+	if err := s.SubResourceClient.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...); err != nil {
+		return err
+	}
+	s.client.record(ActionPatch, s.subResource, live, obj)
+	return nil
+}
@@ -0,0 +1,119 @@
+package dryrun
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dryRunServer emulates the dry-run support of the API server, which the
+// fake client doesn't have. The dry-run writes are counted and not
+// persisted.
+type dryRunServer struct {
+	client.Client
+	dryRuns int
+}
+
+func (s *dryRunServer) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	o := &client.CreateOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) > 0 {
+		s.dryRuns++
+		return nil
+	}
+	return s.Client.Create(ctx, obj, opts...)
+}
+
+func (s *dryRunServer) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	o := &client.UpdateOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) > 0 {
+		s.dryRuns++
+		return nil
+	}
+	return s.Client.Update(ctx, obj, opts...)
+}
+
+func (s *dryRunServer) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	o := &client.DeleteOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) > 0 {
+		s.dryRuns++
+		return s.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	}
+	return s.Client.Delete(ctx, obj, opts...)
+}
+
+func TestClient(t *testing.T) {
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+		Data:       map[string]string{"a": "1"},
+	}
+	server := &dryRunServer{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build(),
+	}
+	recorder := NewRecorder()
+	c := NewClient(server, recorder)
+	ctx := context.Background()
+	cmGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	// Create.
+	created := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "default"},
+		Data:       map[string]string{"b": "2"},
+	}
+	assert.Nil(t, c.Create(ctx, created))
+
+	// Update.
+	updated := &corev1.ConfigMap{}
+	assert.Nil(t, server.Get(ctx, client.ObjectKeyFromObject(existing), updated))
+	updated.Data["a"] = "2"
+	assert.Nil(t, c.Update(ctx, updated))
+
+	// Delete.
+	assert.Nil(t, c.Delete(ctx, existing))
+
+	assert.Equal(t, 3, server.dryRuns)
+
+	changes := recorder.Changes()
+	assert.Len(t, changes, 3)
+
+	assert.Equal(t, ActionCreate, changes[0].Action)
+	assert.Equal(t, cmGVK, changes[0].GroupVersionKind)
+	assert.Equal(t, "new", changes[0].Name)
+	assert.True(t, strings.Contains(changes[0].Diff, `"b": string("2")`), changes[0].Diff)
+
+	assert.Equal(t, ActionUpdate, changes[1].Action)
+	assert.Equal(t, "existing", changes[1].Name)
+	assert.True(t, strings.Contains(changes[1].Diff, `string("1")`), changes[1].Diff)
+	assert.True(t, strings.Contains(changes[1].Diff, `string("2")`), changes[1].Diff)
+	assert.False(t, strings.Contains(changes[1].Diff, "resourceVersion"), changes[1].Diff)
+
+	assert.Equal(t, Change{Action: ActionDelete, GroupVersionKind: cmGVK, Namespace: "default", Name: "existing"}, changes[2])
+
+	// Nothing was changed.
+	live := &corev1.ConfigMap{}
+	assert.Nil(t, server.Get(ctx, client.ObjectKeyFromObject(existing), live))
+	assert.Equal(t, "1", live.Data["a"])
+	assert.NotNil(t, server.Get(ctx, client.ObjectKeyFromObject(created), &corev1.ConfigMap{}))
+}
+
+func TestClientFor(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+
+	assert.Equal(t, c, ClientFor(context.Background(), c))
+	assert.False(t, IsDryRun(context.Background()))
+
+	ctx := WithRecorder(context.Background(), NewRecorder())
+	assert.True(t, IsDryRun(ctx))
+	_, ok := ClientFor(ctx, c).(*Client)
+	assert.True(t, ok)
+}
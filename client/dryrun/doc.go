@@ -0,0 +1,6 @@
+// Package dryrun provides a k8s client that performs all the write
+// operations in dry-run mode and records the changes that would have been
+// made. The dry-run mode is propagated through the context, with a Recorder
+// of the changes. Code that writes to the API server can use ClientFor to get
+// a dry-run client when the context is in dry-run mode.
+package dryrun
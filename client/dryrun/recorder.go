@@ -0,0 +1,81 @@
+package dryrun
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Action is the type of a change.
+type Action string
+
+const (
+	ActionCreate      Action = "Create"
+	ActionUpdate      Action = "Update"
+	ActionPatch       Action = "Patch"
+	ActionDelete      Action = "Delete"
+	ActionDeleteAllOf Action = "DeleteAllOf"
+)
+
+// Change is a change that would have been made to an object.
+type Change struct {
+	// Action is the type of the change.
+	Action Action
+	// GroupVersionKind is the GVK of the object.
+	GroupVersionKind schema.GroupVersionKind
+	// Namespace is the namespace of the object.
+	Namespace string
+	// Name is the name of the object. It's empty for DeleteAllOf.
+	Name string
+	// Subresource is the name of the subresource changed, if any.
+	Subresource string
+	// Diff is the diff between the live object and the object returned by
+	// the dry-run call. It's empty for the deletions.
+	Diff string
+}
+
+// Recorder records the changes of the dry-run calls. It's safe for
+// concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record records a change.
+func (r *Recorder) Record(c Change) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, c)
+}
+
+// Changes returns the recorded changes in the order they were recorded.
+func (r *Recorder) Changes() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Change{}, r.changes...)
+}
+
+type contextKey struct{}
+
+// WithRecorder returns a context in dry-run mode with the given Recorder.
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+// RecorderFrom returns the Recorder of a context in dry-run mode, or nil if
+// the context isn't in dry-run mode.
+func RecorderFrom(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(contextKey{}).(*Recorder)
+	return r
+}
+
+// IsDryRun tells if the given context is in dry-run mode.
+func IsDryRun(ctx context.Context) bool {
+	return RecorderFrom(ctx) != nil
+}
@@ -0,0 +1,153 @@
+package conditions
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Standard condition types.
+const (
+	// TypeReady indicates that the object is fully reconciled and all its
+	// components are ready. It's a summary of the other conditions.
+	TypeReady = "Ready"
+	// TypeProgressing indicates that the object is being moved towards the
+	// desired state and the work is not finished yet.
+	TypeProgressing = "Progressing"
+	// TypeDegraded indicates that the last reconciliation failed and the
+	// object is not in the desired state.
+	TypeDegraded = "Degraded"
+	// TypeReconciling indicates that the reconciler has more work to do on
+	// the object and another reconciliation is expected.
+	TypeReconciling = "Reconciling"
+	// TypeCleanup indicates the progress of the staged cleanup of an object
+	// being deleted. It's False with the last completed or failed cleanup
+	// stage in the message while the cleanup is not complete, and True once
+	// the last stage completed.
+	TypeCleanup = "Cleanup"
+)
+
+// Standard condition reasons.
+const (
+	ReasonReconcileSucceeded = "ReconcileSucceeded"
+	ReasonProgressing        = "Progressing"
+	ReasonOperateFailed      = "OperateFailed"
+	ReasonCleanupFailed      = "CleanupFailed"
+	ReasonCleanupTimedOut    = "CleanupTimedOut"
+	ReasonCleanupInProgress  = "CleanupInProgress"
+	ReasonCleanupCompleted   = "CleanupCompleted"
+	ReasonDeleting           = "Deleting"
+	ReasonConditionUnknown   = "ConditionUnknown"
+)
+
+// Getter is implemented by objects that expose their status conditions.
+type Getter interface {
+	client.Object
+
+	// GetConditions returns the status conditions of the object.
+	GetConditions() []metav1.Condition
+}
+
+// Setter is implemented by objects whose status conditions can be set.
+type Setter interface {
+	Getter
+
+	// SetConditions sets the status conditions of the object.
+	SetConditions([]metav1.Condition)
+}
+
+// Get returns the condition of the given type, or nil if not found.
+func Get(obj Getter, conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(obj.GetConditions(), conditionType)
+}
+
+// IsTrue returns true if the condition of the given type is True.
+func IsTrue(obj Getter, conditionType string) bool {
+	return meta.IsStatusConditionTrue(obj.GetConditions(), conditionType)
+}
+
+// IsFalse returns true if the condition of the given type is False.
+func IsFalse(obj Getter, conditionType string) bool {
+	return meta.IsStatusConditionFalse(obj.GetConditions(), conditionType)
+}
+
+// Set sets the given condition on the object with the current generation of
+// the object as the observed generation. The last transition time is updated
+// only when the status of the condition changes.
+func Set(obj Setter, condition metav1.Condition) {
+	condition.ObservedGeneration = obj.GetGeneration()
+	conditions := obj.GetConditions()
+	meta.SetStatusCondition(&conditions, condition)
+	obj.SetConditions(conditions)
+}
+
+// MarkTrue sets a True condition of the given type on the object.
+func MarkTrue(obj Setter, conditionType, reason, messageFormat string, messageArgs ...interface{}) {
+	Set(obj, metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: fmt.Sprintf(messageFormat, messageArgs...),
+	})
+}
+
+// MarkFalse sets a False condition of the given type on the object.
+func MarkFalse(obj Setter, conditionType, reason, messageFormat string, messageArgs ...interface{}) {
+	Set(obj, metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: fmt.Sprintf(messageFormat, messageArgs...),
+	})
+}
+
+// Delete removes the condition of the given type from the object.
+func Delete(obj Setter, conditionType string) {
+	conditions := obj.GetConditions()
+	meta.RemoveStatusCondition(&conditions, conditionType)
+	obj.SetConditions(conditions)
+}
+
+// Summarize returns a Ready condition that summarizes the given condition
+// types of the object. All the given conditions are expected to have a
+// positive polarity, i.e. True is the healthy state. The summary is True only
+// when all the conditions are True. Otherwise, it's False with the reason of
+// the first unhealthy condition and a message listing all the unhealthy
+// conditions. A missing condition is considered unhealthy.
+func Summarize(obj Getter, conditionTypes ...string) metav1.Condition {
+	summary := metav1.Condition{
+		Type:               TypeReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             ReasonReconcileSucceeded,
+		ObservedGeneration: obj.GetGeneration(),
+	}
+
+	unhealthy := []string{}
+	for _, t := range conditionTypes {
+		c := Get(obj, t)
+		if c != nil && c.Status == metav1.ConditionTrue {
+			continue
+		}
+
+		reason := ReasonConditionUnknown
+		message := "condition not found"
+		if c != nil {
+			reason = c.Reason
+			message = c.Message
+		}
+
+		// Use the reason of the first unhealthy condition.
+		if len(unhealthy) == 0 {
+			summary.Status = metav1.ConditionFalse
+			summary.Reason = reason
+		}
+		unhealthy = append(unhealthy, fmt.Sprintf("%s: %s", t, message))
+	}
+
+	summary.Message = strings.Join(unhealthy, "; ")
+
+	return summary
+}
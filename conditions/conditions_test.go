@@ -0,0 +1,201 @@
+package conditions
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+func newGame() *tdv1alpha1.Game {
+	return &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-game",
+			Namespace:  "test-ns",
+			Generation: 3,
+		},
+	}
+}
+
+func TestSet(t *testing.T) {
+	game := newGame()
+
+	MarkTrue(game, "Foo", "FooReady", "foo is %s", "ready")
+	c := Get(game, "Foo")
+	assert.NotNil(t, c)
+	assert.Equal(t, metav1.ConditionTrue, c.Status)
+	assert.Equal(t, "FooReady", c.Reason)
+	assert.Equal(t, "foo is ready", c.Message)
+	assert.Equal(t, int64(3), c.ObservedGeneration)
+	assert.True(t, IsTrue(game, "Foo"))
+
+	// Generation change is reflected in the condition.
+	game.SetGeneration(4)
+	MarkFalse(game, "Foo", "FooNotReady", "")
+	c = Get(game, "Foo")
+	assert.Equal(t, int64(4), c.ObservedGeneration)
+	assert.True(t, IsFalse(game, "Foo"))
+
+	Delete(game, "Foo")
+	assert.Nil(t, Get(game, "Foo"))
+}
+
+func TestSummarize(t *testing.T) {
+	testcases := []struct {
+		name        string
+		conditions  func(*tdv1alpha1.Game)
+		types       []string
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:       "no summary types",
+			conditions: func(g *tdv1alpha1.Game) {},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: ReasonReconcileSucceeded,
+		},
+		{
+			name: "all true",
+			conditions: func(g *tdv1alpha1.Game) {
+				MarkTrue(g, "A", "AReady", "")
+				MarkTrue(g, "B", "BReady", "")
+			},
+			types:      []string{"A", "B"},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: ReasonReconcileSucceeded,
+		},
+		{
+			name: "some false",
+			conditions: func(g *tdv1alpha1.Game) {
+				MarkTrue(g, "A", "AReady", "")
+				MarkFalse(g, "B", "BNotReady", "b is broken")
+				MarkFalse(g, "C", "CNotReady", "c is broken")
+			},
+			types:       []string{"A", "B", "C"},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "BNotReady",
+			wantMessage: "B: b is broken; C: c is broken",
+		},
+		{
+			name: "missing condition",
+			conditions: func(g *tdv1alpha1.Game) {
+				MarkTrue(g, "A", "AReady", "")
+			},
+			types:       []string{"A", "B"},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  ReasonConditionUnknown,
+			wantMessage: "B: condition not found",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			game := newGame()
+			tc.conditions(game)
+
+			summary := Summarize(game, tc.types...)
+			assert.Equal(t, TypeReady, summary.Type)
+			assert.Equal(t, tc.wantStatus, summary.Status)
+			assert.Equal(t, tc.wantReason, summary.Reason)
+			assert.Equal(t, tc.wantMessage, summary.Message)
+			assert.Equal(t, int64(3), summary.ObservedGeneration)
+		})
+	}
+}
+
+func TestReasonForError(t *testing.T) {
+	someErr := errors.New("some error")
+
+	assert.Equal(t, "Default", ReasonForError(someErr, "Default"))
+	assert.Equal(t, "Custom", ReasonForError(WithReason(someErr, "Custom"), "Default"))
+	// Reason is found in a wrapped error chain.
+	wrapped := fmt.Errorf("outer: %w", WithReason(someErr, "Custom"))
+	assert.Equal(t, "Custom", ReasonForError(wrapped, "Default"))
+	assert.True(t, errors.Is(wrapped, someErr))
+	assert.Nil(t, WithReason(nil, "Custom"))
+}
+
+func TestSetReconcileConditions(t *testing.T) {
+	testcases := []struct {
+		name            string
+		requeue         bool
+		err             error
+		summaryTypes    []string
+		wantReady       metav1.ConditionStatus
+		wantReadyReason string
+		wantProgressing metav1.ConditionStatus
+		wantDegraded    metav1.ConditionStatus
+		wantReconciling metav1.ConditionStatus
+	}{
+		{
+			name:            "success",
+			wantReady:       metav1.ConditionTrue,
+			wantReadyReason: ReasonReconcileSucceeded,
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionFalse,
+			wantReconciling: metav1.ConditionFalse,
+		},
+		{
+			name:            "success with unhealthy child",
+			summaryTypes:    []string{"ChildReady"},
+			wantReady:       metav1.ConditionFalse,
+			wantReadyReason: ReasonConditionUnknown,
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionFalse,
+			wantReconciling: metav1.ConditionFalse,
+		},
+		{
+			name:            "requeue",
+			requeue:         true,
+			wantReady:       metav1.ConditionFalse,
+			wantReadyReason: ReasonProgressing,
+			wantProgressing: metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantReconciling: metav1.ConditionTrue,
+		},
+		{
+			name:            "error",
+			err:             errors.New("some error"),
+			wantReady:       metav1.ConditionFalse,
+			wantReadyReason: ReasonOperateFailed,
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionTrue,
+			wantReconciling: metav1.ConditionTrue,
+		},
+		{
+			name:            "error with reason",
+			requeue:         true,
+			err:             WithReason(errors.New("some error"), "ExternalAPIDown"),
+			wantReady:       metav1.ConditionFalse,
+			wantReadyReason: "ExternalAPIDown",
+			wantProgressing: metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionTrue,
+			wantReconciling: metav1.ConditionTrue,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			game := newGame()
+			SetReconcileConditions(game, tc.requeue, tc.err, ReasonOperateFailed, tc.summaryTypes...)
+
+			ready := Get(game, TypeReady)
+			assert.NotNil(t, ready)
+			assert.Equal(t, tc.wantReady, ready.Status)
+			assert.Equal(t, tc.wantReadyReason, ready.Reason)
+			assert.Equal(t, tc.wantProgressing, Get(game, TypeProgressing).Status)
+			assert.Equal(t, tc.wantDegraded, Get(game, TypeDegraded).Status)
+			assert.Equal(t, tc.wantReconciling, Get(game, TypeReconciling).Status)
+			for _, c := range game.GetConditions() {
+				assert.Equal(t, int64(3), c.ObservedGeneration)
+			}
+		})
+	}
+}
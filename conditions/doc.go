@@ -0,0 +1,5 @@
+// Package conditions provides helpers to manage the standard status
+// conditions of an object. It defines the condition types and reasons used
+// across the toolkit, and functions to set, query and summarize conditions
+// with the observed generation of the object.
+package conditions
@@ -0,0 +1,73 @@
+package conditions
+
+import (
+	"errors"
+)
+
+// Reasoner is implemented by errors that carry a condition reason.
+type Reasoner interface {
+	Reason() string
+}
+
+// reasonedError is an error with a condition reason.
+type reasonedError struct {
+	error
+	reason string
+}
+
+func (e *reasonedError) Reason() string { return e.reason }
+func (e *reasonedError) Unwrap() error  { return e.error }
+
+// WithReason wraps the given error with a condition reason. The reason is
+// used in the conditions set by the reconciler when the error is returned
+// from the reconciliation.
+func WithReason(err error, reason string) error {
+	if err == nil {
+		return nil
+	}
+	return &reasonedError{error: err, reason: reason}
+}
+
+// ReasonForError returns the condition reason for the given error. If the
+// error, or any error in its chain, implements Reasoner, its reason is
+// returned, else the default reason.
+func ReasonForError(err error, defaultReason string) string {
+	var r Reasoner
+	if errors.As(err, &r) && r.Reason() != "" {
+		return r.Reason()
+	}
+	return defaultReason
+}
+
+// SetReconcileConditions sets the standard conditions on the object based on
+// the outcome of a reconciliation. requeue tells if the reconciliation
+// requested another run, err is the reconciliation error and failureReason is
+// the reason used for errors that don't carry a reason. The Ready condition
+// of a successful reconciliation is a summary of the given summaryTypes.
+func SetReconcileConditions(obj Setter, requeue bool, err error, failureReason string, summaryTypes ...string) {
+	switch {
+	case err != nil:
+		reason := ReasonForError(err, failureReason)
+		MarkTrue(obj, TypeDegraded, reason, "%v", err)
+		MarkFalse(obj, TypeProgressing, reason, "Reconciliation failed")
+		MarkTrue(obj, TypeReconciling, reason, "Retrying after failure")
+		MarkFalse(obj, TypeReady, reason, "%v", err)
+	case requeue:
+		MarkFalse(obj, TypeDegraded, ReasonProgressing, "")
+		MarkTrue(obj, TypeProgressing, ReasonProgressing, "Reconciliation in progress")
+		MarkTrue(obj, TypeReconciling, ReasonProgressing, "Reconciliation in progress")
+		MarkFalse(obj, TypeReady, ReasonProgressing, "Reconciliation in progress")
+	default:
+		MarkFalse(obj, TypeDegraded, ReasonReconcileSucceeded, "")
+		MarkFalse(obj, TypeProgressing, ReasonReconcileSucceeded, "")
+		MarkFalse(obj, TypeReconciling, ReasonReconcileSucceeded, "")
+		Set(obj, Summarize(obj, summaryTypes...))
+	}
+}
+
+// MarkDeleting sets the standard conditions on an object that's being
+// deleted.
+func MarkDeleting(obj Setter) {
+	MarkFalse(obj, TypeReady, ReasonDeleting, "Object is being deleted")
+	MarkTrue(obj, TypeReconciling, ReasonDeleting, "Object is being deleted")
+}
@@ -3,4 +3,8 @@ package constant
 const (
 	// LibraryName is the full name/root import path of the operator-toolkit.
 	LibraryName = "github.com/ondat/operator-toolkit"
+
+	// AnnotationPrefix is the prefix of the annotations read by the
+	// operator-toolkit components.
+	AnnotationPrefix = "operator-toolkit.ondat.io/"
 )
@@ -0,0 +1,213 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/ondat/operator-toolkit/conditions"
+)
+
+// CleanupFunc cleans up after an object marked for deletion.
+type CleanupFunc func(context.Context, client.Object) (ctrl.Result, error)
+
+// CleanupStage is a named stage of the cleanup of an object with its own
+// finalizer. The finalizer is removed from the object once the stage cleanup
+// completes, independently of the other stages.
+type CleanupStage struct {
+	// Name is the name of the stage.
+	Name string
+	// Finalizer is the finalizer added to the object for the stage.
+	Finalizer string
+	// Cleanup is the cleanup of the stage.
+	Cleanup CleanupFunc
+}
+
+// validateCleanupStages checks that the cleanup stages have a name, a cleanup
+// func and a finalizer, and that the names and finalizers are unique.
+func validateCleanupStages(stages []CleanupStage) error {
+	names := map[string]bool{}
+	finalizers := map[string]bool{}
+	for i, stage := range stages {
+		if stage.Name == "" {
+			return fmt.Errorf("cleanup stage %d has no name", i)
+		}
+		if stage.Finalizer == "" {
+			return fmt.Errorf("cleanup stage %q has no finalizer", stage.Name)
+		}
+		if stage.Cleanup == nil {
+			return fmt.Errorf("cleanup stage %q has no cleanup func", stage.Name)
+		}
+		if names[stage.Name] {
+			return fmt.Errorf("duplicate cleanup stage %q", stage.Name)
+		}
+		if finalizers[stage.Finalizer] {
+			return fmt.Errorf("cleanup stage %q uses duplicate finalizer %q", stage.Name, stage.Finalizer)
+		}
+		names[stage.Name] = true
+		finalizers[stage.Finalizer] = true
+	}
+	return nil
+}
+
+// finalizers returns the finalizers that must be on an object that's not
+// being deleted.
+func (c *CompositeReconciler) finalizers() []string {
+	if len(c.cleanupStages) == 0 {
+		return []string{c.finalizerName}
+	}
+	result := make([]string, 0, len(c.cleanupStages))
+	for _, stage := range c.cleanupStages {
+		result = append(result, stage.Finalizer)
+	}
+	return result
+}
+
+// legacyFinalizer tells if the object has the finalizer of the reconciler
+// from a setup without cleanup stages, which needs to be migrated to the
+// stage finalizers.
+func (c *CompositeReconciler) legacyFinalizer(obj client.Object) bool {
+	if len(c.cleanupStages) == 0 || !controllerutil.ContainsFinalizer(obj, c.finalizerName) {
+		return false
+	}
+	return !contains(c.finalizers(), c.finalizerName)
+}
+
+// ensureFinalizers adds the missing finalizers to the object and migrates the
+// legacy finalizer to the stage finalizers. It returns true if the finalizers
+// of the object changed.
+func (c *CompositeReconciler) ensureFinalizers(obj client.Object) bool {
+	changed := false
+	if c.legacyFinalizer(obj) {
+		controllerutil.RemoveFinalizer(obj, c.finalizerName)
+		changed = true
+	}
+	for _, finalizer := range c.finalizers() {
+		if controllerutil.AddFinalizer(obj, finalizer) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// runCleanupStages runs the cleanup stages of an object marked for deletion.
+// The first stage with its finalizer on the object is run and its finalizer
+// is removed once it completes. An object with the legacy finalizer has no
+// stage finalizers, as finalizers can't be added to an object being deleted,
+// so all the stages are run in order and the legacy finalizer is removed once
+// they all complete.
+func (c *CompositeReconciler) runCleanupStages(ctx context.Context, obj client.Object) (updated bool, result ctrl.Result, reterr error) {
+	ctx, span, log := c.inst.Start(ctx, "runCleanupStages")
+	defer span.End()
+
+	// Save the object before changing the Cleanup condition in memory.
+	oldObj := obj.DeepCopyObject()
+
+	legacy := c.legacyFinalizer(obj)
+	for i, stage := range c.cleanupStages {
+		if !legacy && !controllerutil.ContainsFinalizer(obj, stage.Finalizer) {
+			continue
+		}
+
+		span.AddEvent("Run cleanup stage", trace.WithAttributes(attribute.String("stage", stage.Name)))
+		finalizer := stage.Finalizer
+		if legacy {
+			finalizer = c.finalizerName
+		}
+		result, reterr = stage.Cleanup(ctx, obj)
+		if reterr != nil {
+			log.Error(reterr, "failed to cleanup", "stage", stage.Name)
+			if reterr = c.cleanupFailed(ctx, obj, finalizer, reterr); reterr != nil {
+				c.markCleanupStage(obj, i, reterr)
+				return
+			}
+		}
+		if legacy {
+			continue
+		}
+
+		// Stage completed, record it and remove the stage finalizer.
+		span.AddEvent("Cleanup stage completed, remove finalizer", trace.WithAttributes(attribute.String("stage", stage.Name)))
+		c.writeCleanupStage(ctx, oldObj, obj, i)
+		return c.removeFinalizer(ctx, obj, stage.Finalizer)
+	}
+
+	if legacy {
+		span.AddEvent("Cleanup stages completed, remove legacy finalizer")
+		c.writeCleanupStage(ctx, oldObj, obj, len(c.cleanupStages)-1)
+		return c.removeFinalizer(ctx, obj, c.finalizerName)
+	}
+
+	span.AddEvent("No cleanup stage finalizer found, no-op")
+	return
+}
+
+// removeFinalizer removes the given finalizer from the object and updates the
+// object.
+func (c *CompositeReconciler) removeFinalizer(ctx context.Context, obj client.Object, finalizer string) (updated bool, result ctrl.Result, reterr error) {
+	ctx, span, log := c.inst.Start(ctx, "removeFinalizer")
+	defer span.End()
+
+	controllerutil.RemoveFinalizer(obj, finalizer)
+	if reterr = c.client.Update(ctx, obj); reterr != nil {
+		log.Error(reterr, "failed to remove finalizer", "finalizer", finalizer)
+		return
+	}
+	// Mark API object update.
+	updated = true
+	result = ctrl.Result{Requeue: true}
+	return
+}
+
+// markCleanupStage sets the Cleanup condition of the object with the
+// progress of the stage at the given index, completed if err is nil or else
+// failed. It returns false if the condition management is disabled or the
+// object doesn't support conditions.
+func (c *CompositeReconciler) markCleanupStage(obj client.Object, index int, err error) bool {
+	if !c.manageConditions {
+		return false
+	}
+	setter, ok := obj.(conditions.Setter)
+	if !ok {
+		return false
+	}
+
+	stage := c.cleanupStages[index]
+	total := len(c.cleanupStages)
+	switch {
+	case err != nil:
+		conditions.MarkFalse(setter, conditions.TypeCleanup, conditions.ReasonForError(err, conditions.ReasonCleanupFailed),
+			"Cleanup stage %q (%d/%d) failed: %v", stage.Name, index+1, total, err)
+	case index+1 < total:
+		conditions.MarkFalse(setter, conditions.TypeCleanup, conditions.ReasonCleanupInProgress,
+			"Cleanup stage %q (%d/%d) completed, next stage %q", stage.Name, index+1, total, c.cleanupStages[index+1].Name)
+	default:
+		conditions.MarkTrue(setter, conditions.TypeCleanup, conditions.ReasonCleanupCompleted,
+			"Cleanup stage %q (%d/%d) completed", stage.Name, index+1, total)
+	}
+	return true
+}
+
+// writeCleanupStage records the completion of the stage at the given index in
+// the Cleanup condition of the object and writes the status, before the
+// finalizer of the stage is removed. The deferred status update of the
+// reconciliation is skipped once the finalizer is removed. A failure to write
+// the status doesn't block the removal of the finalizer.
+func (c *CompositeReconciler) writeCleanupStage(ctx context.Context, oldObj runtime.Object, obj client.Object, index int) {
+	ctx, span, log := c.inst.Start(ctx, "writeCleanupStage")
+	defer span.End()
+
+	if !c.markCleanupStage(obj, index, nil) {
+		return
+	}
+	if err := c.writeStatus(ctx, oldObj, obj); err != nil {
+		span.RecordError(err)
+		log.Error(err, "failed to write cleanup stage status", "stage", c.cleanupStages[index].Name)
+	}
+}
@@ -0,0 +1,288 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/conditions"
+	"github.com/ondat/operator-toolkit/controller/composite/v1/mocks"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+func TestCleanupStages(t *testing.T) {
+	// Create a scheme with testdata scheme info.
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	legacyFinalizer := "test-finalizer"
+	externalFinalizer := "external.example.com"
+	backupFinalizer := "backup.example.com"
+	someFinalizerX := "some-finalizer-x"
+	someErr := errors.New("some cleanup error")
+
+	newGame := func(deleted bool, finalizers ...string) *tdv1alpha1.Game {
+		g := &tdv1alpha1.Game{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "my-game",
+				Namespace:  "default",
+				Finalizers: finalizers,
+			},
+		}
+		if deleted {
+			g.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		}
+		return g
+	}
+
+	cases := []struct {
+		name           string
+		obj            *tdv1alpha1.Game
+		failStage      string
+		wantCalls      []string
+		wantFinalizers []string
+		wantUpdated    bool
+		wantErr        error
+		// noConditions disables the condition management.
+		noConditions        bool
+		wantCondition       string
+		wantConditionStatus metav1.ConditionStatus
+		wantConditionReason string
+	}{
+		{
+			name:           "add stage finalizers",
+			obj:            newGame(false, someFinalizerX),
+			wantFinalizers: []string{someFinalizerX, externalFinalizer, backupFinalizer},
+			wantUpdated:    true,
+		},
+		{
+			name:           "keep existing stage finalizers",
+			obj:            newGame(false, externalFinalizer, backupFinalizer),
+			wantFinalizers: []string{externalFinalizer, backupFinalizer},
+		},
+		{
+			name:           "migrate legacy finalizer",
+			obj:            newGame(false, someFinalizerX, legacyFinalizer),
+			wantFinalizers: []string{someFinalizerX, externalFinalizer, backupFinalizer},
+			wantUpdated:    true,
+		},
+		{
+			name:                "run first stage",
+			obj:                 newGame(true, externalFinalizer, backupFinalizer),
+			wantCalls:           []string{"external"},
+			wantFinalizers:      []string{backupFinalizer},
+			wantUpdated:         true,
+			wantCondition:       `Cleanup stage "external" (1/2) completed, next stage "backup"`,
+			wantConditionStatus: metav1.ConditionFalse,
+			wantConditionReason: conditions.ReasonCleanupInProgress,
+		},
+		{
+			name:                "run next stage",
+			obj:                 newGame(true, someFinalizerX, backupFinalizer),
+			wantCalls:           []string{"backup"},
+			wantFinalizers:      []string{someFinalizerX},
+			wantUpdated:         true,
+			wantCondition:       `Cleanup stage "backup" (2/2) completed`,
+			wantConditionStatus: metav1.ConditionTrue,
+			wantConditionReason: conditions.ReasonCleanupCompleted,
+		},
+		{
+			name:                "stage failed",
+			obj:                 newGame(true, externalFinalizer, backupFinalizer),
+			failStage:           "external",
+			wantCalls:           []string{"external"},
+			wantFinalizers:      []string{externalFinalizer, backupFinalizer},
+			wantErr:             someErr,
+			wantCondition:       `Cleanup stage "external" (1/2) failed: some cleanup error`,
+			wantConditionStatus: metav1.ConditionFalse,
+			wantConditionReason: conditions.ReasonCleanupFailed,
+		},
+		{
+			name:           "stage failed without conditions",
+			obj:            newGame(true, externalFinalizer, backupFinalizer),
+			failStage:      "external",
+			wantCalls:      []string{"external"},
+			wantFinalizers: []string{externalFinalizer, backupFinalizer},
+			wantErr:        someErr,
+			noConditions:   true,
+		},
+		{
+			name:           "stage completed without conditions",
+			obj:            newGame(true, externalFinalizer, backupFinalizer),
+			wantCalls:      []string{"external"},
+			wantFinalizers: []string{backupFinalizer},
+			wantUpdated:    true,
+			noConditions:   true,
+		},
+		{
+			name:           "no stage finalizer",
+			obj:            newGame(true, someFinalizerX),
+			wantFinalizers: []string{someFinalizerX},
+		},
+		{
+			name:                "run all stages with legacy finalizer",
+			obj:                 newGame(true, someFinalizerX, legacyFinalizer),
+			wantCalls:           []string{"external", "backup"},
+			wantFinalizers:      []string{someFinalizerX},
+			wantUpdated:         true,
+			wantCondition:       `Cleanup stage "backup" (2/2) completed`,
+			wantConditionStatus: metav1.ConditionTrue,
+			wantConditionReason: conditions.ReasonCleanupCompleted,
+		},
+		{
+			name:                "stage failed with legacy finalizer",
+			obj:                 newGame(true, legacyFinalizer),
+			failStage:           "backup",
+			wantCalls:           []string{"external", "backup"},
+			wantFinalizers:      []string{legacyFinalizer},
+			wantErr:             someErr,
+			wantCondition:       `Cleanup stage "backup" (2/2) failed: some cleanup error`,
+			wantConditionStatus: metav1.ConditionFalse,
+			wantConditionReason: conditions.ReasonCleanupFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithObjects(tc.obj).
+				WithScheme(scheme).
+				Build()
+
+			// The Controller Cleanup must not be called with cleanup stages.
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			m := mocks.NewMockController(mctrl)
+
+			var calls []string
+			stage := func(name, finalizer string) CleanupStage {
+				return CleanupStage{
+					Name:      name,
+					Finalizer: finalizer,
+					Cleanup: func(context.Context, client.Object) (ctrl.Result, error) {
+						calls = append(calls, name)
+						if name == tc.failStage {
+							return ctrl.Result{}, someErr
+						}
+						return ctrl.Result{}, nil
+					},
+				}
+			}
+
+			opts := []CompositeReconcilerOption{
+				WithScheme(scheme),
+				WithFinalizer(legacyFinalizer),
+				WithClient(cli),
+				WithCleanupStages(
+					stage("external", externalFinalizer),
+					stage("backup", backupFinalizer),
+				),
+			}
+			if !tc.noConditions {
+				opts = append(opts, WithConditions())
+			}
+			cr := CompositeReconciler{}
+			err := cr.Init(nil, m, nil, opts...)
+			assert.Nil(t, err)
+
+			_, updated, _, err := cr.cleanupHandler(context.Background(), tc.obj)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCalls, calls, "cleanup stage calls")
+			assert.Equal(t, tc.wantFinalizers, tc.obj.GetFinalizers(), "finalizers after cleanupHandler call")
+			assert.Equal(t, tc.wantUpdated, updated, "updated result")
+
+			checkCondition := func(obj conditions.Getter) {
+				cond := conditions.Get(obj, conditions.TypeCleanup)
+				if tc.wantCondition == "" {
+					assert.Nil(t, cond)
+				} else if assert.NotNil(t, cond) {
+					assert.Equal(t, tc.wantConditionStatus, cond.Status)
+					assert.Equal(t, tc.wantConditionReason, cond.Reason)
+					assert.Equal(t, tc.wantCondition, cond.Message)
+				}
+			}
+			checkCondition(tc.obj)
+
+			// Check the finalizers and the stage progress persisted in the
+			// API.
+			if tc.wantUpdated {
+				got := &tdv1alpha1.Game{}
+				err := cli.Get(context.Background(), client.ObjectKeyFromObject(tc.obj), got)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.wantFinalizers, got.GetFinalizers())
+				checkCondition(got)
+			}
+		})
+	}
+}
+
+func TestValidateCleanupStages(t *testing.T) {
+	cleanup := func(context.Context, client.Object) (ctrl.Result, error) { return ctrl.Result{}, nil }
+
+	cases := []struct {
+		name    string
+		stages  []CleanupStage
+		wantErr string
+	}{
+		{
+			name: "valid",
+			stages: []CleanupStage{
+				{Name: "a", Finalizer: "a.example.com", Cleanup: cleanup},
+				{Name: "b", Finalizer: "b.example.com", Cleanup: cleanup},
+			},
+		},
+		{
+			name:    "no name",
+			stages:  []CleanupStage{{Finalizer: "a.example.com", Cleanup: cleanup}},
+			wantErr: "cleanup stage 0 has no name",
+		},
+		{
+			name:    "no finalizer",
+			stages:  []CleanupStage{{Name: "a", Cleanup: cleanup}},
+			wantErr: `cleanup stage "a" has no finalizer`,
+		},
+		{
+			name:    "no cleanup",
+			stages:  []CleanupStage{{Name: "a", Finalizer: "a.example.com"}},
+			wantErr: `cleanup stage "a" has no cleanup func`,
+		},
+		{
+			name: "duplicate name",
+			stages: []CleanupStage{
+				{Name: "a", Finalizer: "a.example.com", Cleanup: cleanup},
+				{Name: "a", Finalizer: "b.example.com", Cleanup: cleanup},
+			},
+			wantErr: `duplicate cleanup stage "a"`,
+		},
+		{
+			name: "duplicate finalizer",
+			stages: []CleanupStage{
+				{Name: "a", Finalizer: "a.example.com", Cleanup: cleanup},
+				{Name: "b", Finalizer: "a.example.com", Cleanup: cleanup},
+			},
+			wantErr: `cleanup stage "b" uses duplicate finalizer "a.example.com"`,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCleanupStages(tc.stages)
+			if tc.wantErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package v1
 
 import (
+	"sync"
+	"time"
+
 	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -34,17 +38,85 @@ const (
 	FinalizerCleanup
 )
 
+// AnnotationForceDelete is the annotation of a parent object that, when set
+// to "true", removes the finalizer of the object being deleted if its cleanup
+// fails, regardless of the cleanup timeout.
+const AnnotationForceDelete = constant.AnnotationPrefix + "force-delete"
+
+// Reasons of the events recorded on the parent object when its cleanup
+// doesn't complete.
+const (
+	ReasonCleanupTimedOut       = "CleanupTimedOut"
+	ReasonFinalizerForceRemoved = "FinalizerForceRemoved"
+)
+
+// StatusWriteMode is the method used by the reconciler to write the status of
+// the reconciled object to the API server.
+type StatusWriteMode int
+
+const (
+	// StatusUpdate replaces the whole status subresource with an update
+	// call. This fails with a conflict error if the object was modified by
+	// another writer after it was fetched by the reconciler.
+	StatusUpdate StatusWriteMode = iota
+	// StatusMergePatch writes the changed status fields with a JSON merge
+	// patch computed against the object fetched at the start of the
+	// reconciliation. No resource version check is performed.
+	StatusMergePatch
+	// StatusOptimisticLockPatch is the same as StatusMergePatch but the patch
+	// includes the resource version of the fetched object. The write fails
+	// with a conflict if the object was modified in the meantime.
+	StatusOptimisticLockPatch
+	// StatusServerSideApply writes the status with server-side apply, using
+	// the reconciler name as the field manager. Only the fields owned by the
+	// reconciler are updated and ownership conflicts are forced.
+	StatusServerSideApply
+)
+
 // CompositeReconciler defines a composite reconciler.
 type CompositeReconciler struct {
 	name            string
 	initCondition   metav1.Condition
 	finalizerName   string
 	cleanupStrategy CleanupStrategy
-	ctrlr           Controller
-	prototype       client.Object
-	client          client.Client
-	scheme          *runtime.Scheme
-	inst            *telemetry.Instrumentation
+	statusWriteMode StatusWriteMode
+	// manageConditions enables the management of the standard conditions.
+	manageConditions bool
+	// summaryConditions are the condition types summarized in the Ready
+	// condition.
+	summaryConditions []string
+	// skipUnchangedGeneration enables skipping Operate when the object
+	// generation was already reconciled successfully.
+	skipUnchangedGeneration bool
+	// reconciledGenerations stores the object generations and control
+	// annotations, keyed by the object UID, for which the last Operate was
+	// successful.
+	reconciledGenerations sync.Map
+	// uids stores the UID of the reconciled objects, keyed by namespaced
+	// name, to forget the deleted objects.
+	uids sync.Map
+	// cleanupTimeout is the maximum duration of the cleanup, measured from
+	// the deletion timestamp of the object. Zero means no timeout.
+	cleanupTimeout time.Duration
+	// cleanupStages are the ordered stages of the cleanup, each with its own
+	// finalizer. When empty, the Controller Cleanup is used with the
+	// finalizerName finalizer.
+	cleanupStages []CleanupStage
+	// middlewares wrap the Controller Operate, the first one being the
+	// outermost.
+	middlewares []Middleware
+	// panicRecovery enables the recovery of the panics in Operate and the
+	// middlewares.
+	panicRecovery bool
+	// forceFinalizerRemoval enables removing the finalizer when the cleanup
+	// doesn't complete within cleanupTimeout.
+	forceFinalizerRemoval bool
+	recorder              record.EventRecorder
+	ctrlr                 Controller
+	prototype             client.Object
+	client                client.Client
+	scheme                *runtime.Scheme
+	inst                  *telemetry.Instrumentation
 }
 
 // CompositeReconcilerOption is used to configure CompositeReconciler.
@@ -94,6 +166,103 @@ func WithCleanupStrategy(cleanupStrat CleanupStrategy) CompositeReconcilerOption
 	}
 }
 
+// WithStatusWriteMode sets the StatusWriteMode of the CompositeReconciler.
+func WithStatusWriteMode(mode StatusWriteMode) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.statusWriteMode = mode
+	}
+}
+
+// WithConditions enables the management of the standard status conditions
+// (Ready, Progressing, Degraded and Reconciling) by the CompositeReconciler.
+// The conditions are set after UpdateStatus for objects that implement
+// conditions.Setter. The Ready condition of a successful reconciliation is a
+// summary of the given condition types, usually set by UpdateStatus based on
+// the state of the child objects.
+func WithConditions(summaryTypes ...string) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.manageConditions = true
+		c.summaryConditions = summaryTypes
+	}
+}
+
+// WithSkipUnchangedGeneration enables skipping Operate when the generation of
+// the object is the same as its observed generation and the last Operate on
+// that generation was successful. UpdateStatus still runs on every
+// reconciliation. The last result is kept in memory, so the first
+// reconciliation of an object after a restart always runs Operate. A change
+// in the operator-toolkit control annotations of the object, like a forced
+// rerun of an operand, also runs Operate.
+// NOTE: Changes to the child objects aren't reverted until the next change
+// in the object generation when this is enabled.
+func WithSkipUnchangedGeneration() CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.skipUnchangedGeneration = true
+	}
+}
+
+// WithCleanupTimeout sets the maximum duration of the cleanup of an object
+// with FinalizerCleanup, measured from the deletion timestamp of the object.
+// When the cleanup fails after the timeout, a Warning event is recorded and
+// the cleanup error is returned with the CleanupTimedOut condition reason.
+func WithCleanupTimeout(timeout time.Duration) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.cleanupTimeout = timeout
+	}
+}
+
+// WithForceFinalizerRemoval enables removing the finalizer of an object when
+// its cleanup fails after the cleanup timeout, allowing the object to be
+// deleted. The child objects and external resources that weren't cleaned up
+// are left behind.
+func WithForceFinalizerRemoval() CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.forceFinalizerRemoval = true
+	}
+}
+
+// WithCleanupStages sets the ordered stages of the cleanup of an object with
+// FinalizerCleanup. Each stage adds its own finalizer to the object and the
+// finalizer is removed once the stage cleanup completes. The stages are run
+// one at a time, in the given order. The Controller Cleanup is not called
+// when cleanup stages are set. Objects with the finalizer of the reconciler,
+// from a setup without stages, are migrated to the stage finalizers. With
+// WithConditions, the progress of the stages is tracked in the Cleanup
+// condition.
+func WithCleanupStages(stages ...CleanupStage) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.cleanupStages = stages
+	}
+}
+
+// WithMiddleware adds middlewares around the Controller Operate of the
+// CompositeReconciler. The middlewares run in the given order, after the
+// object is fetched, validated, initialized and its finalizers handled, and
+// before UpdateStatus. A middleware can modify the object, replace the result
+// and error of Operate or skip Operate by not calling the next ReconcileFunc.
+func WithMiddleware(middlewares ...Middleware) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithPanicRecovery enables recovering from a panic in Operate and the
+// middlewares. The panic is returned as a reconcile error, with a Warning
+// event on the object, instead of crashing the manager.
+func WithPanicRecovery() CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.panicRecovery = true
+	}
+}
+
+// WithEventRecorder sets the event recorder of the CompositeReconciler. By
+// default, the event recorder of the manager is used.
+func WithEventRecorder(recorder record.EventRecorder) CompositeReconcilerOption {
+	return func(c *CompositeReconciler) {
+		c.recorder = recorder
+	}
+}
+
 // WithScheme sets the runtime Scheme of the CompositeReconciler.
 func WithScheme(scheme *runtime.Scheme) CompositeReconcilerOption {
 	return func(c *CompositeReconciler) {
@@ -132,6 +301,7 @@ func (c *CompositeReconciler) Init(mgr ctrl.Manager, ctrlr Controller, prototype
 	// Add defaults.
 	c.initCondition = DefaultInitCondition
 	c.cleanupStrategy = OwnerReferenceCleanup
+	c.statusWriteMode = StatusUpdate
 
 	// Run the options to override the defaults.
 	for _, opt := range opts {
@@ -143,6 +313,20 @@ func (c *CompositeReconciler) Init(mgr ctrl.Manager, ctrlr Controller, prototype
 		c.finalizerName = c.name
 	}
 
+	if err := validateCleanupStages(c.cleanupStages); err != nil {
+		return err
+	}
+
+	// The panic recovery is the outermost middleware.
+	if c.panicRecovery {
+		c.middlewares = append([]Middleware{c.recoverPanic}, c.middlewares...)
+	}
+
+	// If event recorder is not provided, use the manager's event recorder.
+	if c.recorder == nil && mgr != nil {
+		c.recorder = mgr.GetEventRecorderFor(c.name)
+	}
+
 	// If instrumentation is nil, create a new instrumentation with default
 	// providers.
 	if c.inst == nil {
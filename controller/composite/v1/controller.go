@@ -52,3 +52,13 @@ type Controller interface {
 	// custom cleanup requirement, the cleanup logic can be defined here.
 	Cleanup(context.Context, client.Object) (result ctrl.Result, err error)
 }
+
+// Forgetter is an optional interface of a Controller that keeps in-memory
+// state about the parent objects, like a CompositeOperator. Forget is called
+// once the reconciler finds that an object it reconciled before no longer
+// exists, regardless of the cleanup strategy, to release the state of the
+// object. The given object is a copy of the prototype with only the name,
+// namespace and UID of the deleted object.
+type Forgetter interface {
+	Forget(context.Context, client.Object)
+}
@@ -3,18 +3,25 @@ package v1
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/ondat/operator-toolkit/conditions"
+	tkctrl "github.com/ondat/operator-toolkit/controller"
 	"github.com/ondat/operator-toolkit/controller/composite/v1/mocks"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
 )
 
@@ -332,3 +339,459 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	initializedGameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-game",
+			Namespace:  "test-ns",
+			Generation: 2,
+		},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{DefaultInitCondition},
+		},
+	}
+
+	testcases := []struct {
+		name            string
+		operateResult   ctrl.Result
+		operateErr      error
+		wantReady       metav1.ConditionStatus
+		wantDegraded    metav1.ConditionStatus
+		wantProgressing metav1.ConditionStatus
+	}{
+		{
+			name:            "operate success",
+			wantReady:       metav1.ConditionTrue,
+			wantDegraded:    metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionFalse,
+		},
+		{
+			name:            "operate requeue",
+			operateResult:   ctrl.Result{RequeueAfter: time.Second},
+			wantReady:       metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionFalse,
+			wantProgressing: metav1.ConditionTrue,
+		},
+		{
+			name:            "operate failure",
+			operateResult:   ctrl.Result{Requeue: true},
+			operateErr:      errors.New("operate error"),
+			wantReady:       metav1.ConditionFalse,
+			wantDegraded:    metav1.ConditionTrue,
+			wantProgressing: metav1.ConditionFalse,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(initializedGameObj.DeepCopy()).
+				Build()
+
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			m := mocks.NewMockController(mctrl)
+			m.EXPECT().Default(gomock.Any(), gomock.Any())
+			m.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(nil)
+			m.EXPECT().Operate(gomock.Any(), gomock.Any()).Return(tc.operateResult, tc.operateErr)
+			m.EXPECT().UpdateStatus(gomock.Any(), gomock.Any())
+
+			cr := &CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, m, &tdv1alpha1.Game{},
+				WithScheme(scheme),
+				WithClient(cli),
+				WithConditions(),
+			))
+
+			_, err := cr.Reconcile(context.Background(), ctrl.Request{NamespacedName: gameNamespacedName})
+			assert.Equal(t, tc.operateErr != nil, err != nil)
+
+			got := &tdv1alpha1.Game{}
+			assert.Nil(t, cli.Get(context.Background(), gameNamespacedName, got))
+			for condType, want := range map[string]metav1.ConditionStatus{
+				conditions.TypeReady:       tc.wantReady,
+				conditions.TypeDegraded:    tc.wantDegraded,
+				conditions.TypeProgressing: tc.wantProgressing,
+			} {
+				c := conditions.Get(got, condType)
+				if assert.NotNil(t, c, "condition %s", condType) {
+					assert.Equal(t, want, c.Status, "condition %s", condType)
+					assert.Equal(t, int64(2), c.ObservedGeneration, "condition %s", condType)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileSkipUnchangedGeneration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	initializedGameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-game",
+			Namespace:  "test-ns",
+			UID:        "game-uid",
+			Generation: 1,
+		},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{DefaultInitCondition},
+		},
+	}
+
+	testcases := []struct {
+		name         string
+		skip         bool
+		operateFirst ctrl.Result
+		// wantOperate is the number of Operate calls expected in the
+		// second reconciliation with the same generation.
+		wantOperate int
+	}{
+		{
+			name:        "skip disabled",
+			wantOperate: 1,
+		},
+		{
+			name:        "skip enabled",
+			skip:        true,
+			wantOperate: 0,
+		},
+		{
+			name:         "skip enabled, last result requeued",
+			skip:         true,
+			operateFirst: ctrl.Result{Requeue: true},
+			wantOperate:  1,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(initializedGameObj.DeepCopy()).
+				Build()
+
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			m := mocks.NewMockController(mctrl)
+			m.EXPECT().Default(gomock.Any(), gomock.Any()).AnyTimes()
+			m.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().UpdateStatus(gomock.Any(), gomock.Any()).AnyTimes()
+
+			opts := []CompositeReconcilerOption{WithScheme(scheme), WithClient(cli)}
+			if tc.skip {
+				opts = append(opts, WithSkipUnchangedGeneration())
+			}
+			cr := &CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, m, &tdv1alpha1.Game{}, opts...))
+
+			ctx := context.Background()
+			req := ctrl.Request{NamespacedName: gameNamespacedName}
+
+			// First reconciliation always operates and records the
+			// observed generation.
+			m.EXPECT().Operate(gomock.Any(), gomock.Any()).Return(tc.operateFirst, nil)
+			_, err := cr.Reconcile(ctx, req)
+			assert.Nil(t, err)
+
+			got := &tdv1alpha1.Game{}
+			assert.Nil(t, cli.Get(ctx, gameNamespacedName, got))
+			assert.Equal(t, int64(1), got.Status.ObservedGeneration)
+
+			// Second reconciliation with the same generation.
+			m.EXPECT().Operate(gomock.Any(), gomock.Any()).Times(tc.wantOperate)
+			_, err = cr.Reconcile(ctx, req)
+			assert.Nil(t, err)
+
+			// A new generation is always operated.
+			got.SetGeneration(2)
+			assert.Nil(t, cli.Update(ctx, got))
+			m.EXPECT().Operate(gomock.Any(), gomock.Any())
+			_, err = cr.Reconcile(ctx, req)
+			assert.Nil(t, err)
+
+			assert.Nil(t, cli.Get(ctx, gameNamespacedName, got))
+			assert.Equal(t, int64(2), got.Status.ObservedGeneration)
+
+			// An object recreated with the same name, generation and
+			// observed generation is operated.
+			assert.Nil(t, cli.Delete(ctx, got))
+			recreated := initializedGameObj.DeepCopy()
+			recreated.SetUID("recreated-uid")
+			recreated.Status.ObservedGeneration = 1
+			assert.Nil(t, cli.Create(ctx, recreated))
+			m.EXPECT().Operate(gomock.Any(), gomock.Any())
+			_, err = cr.Reconcile(ctx, req)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestReconcileMiddleware(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	initializedGameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{DefaultInitCondition},
+		},
+	}
+
+	// calls records the middleware and Operate calls in order.
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next ReconcileFunc) ReconcileFunc {
+			return func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+				calls = append(calls, name+" before")
+				result, err := next(ctx, obj)
+				calls = append(calls, name+" after")
+				return result, err
+			}
+		}
+	}
+	gate := func(next ReconcileFunc) ReconcileFunc {
+		return func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			calls = append(calls, "gate")
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+	wrapErr := func(next ReconcileFunc) ReconcileFunc {
+		return func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			result, err := next(ctx, obj)
+			if err != nil {
+				return result, fmt.Errorf("wrapped: %w", err)
+			}
+			return result, nil
+		}
+	}
+
+	testcases := []struct {
+		name        string
+		middlewares []Middleware
+		operateErr  error
+		wantOperate bool
+		wantCalls   []string
+		wantResult  ctrl.Result
+		wantErr     string
+	}{
+		{
+			name:        "no middleware",
+			wantOperate: true,
+			wantCalls:   []string{"operate"},
+		},
+		{
+			name:        "middlewares in order",
+			middlewares: []Middleware{record("first"), record("second")},
+			wantOperate: true,
+			wantCalls:   []string{"first before", "second before", "operate", "second after", "first after"},
+		},
+		{
+			name:        "operate skipped",
+			middlewares: []Middleware{record("first"), gate},
+			wantCalls:   []string{"first before", "gate", "first after"},
+			wantResult:  ctrl.Result{RequeueAfter: time.Minute},
+		},
+		{
+			name:        "operate error replaced",
+			middlewares: []Middleware{wrapErr},
+			operateErr:  errors.New("operate error"),
+			wantOperate: true,
+			wantCalls:   []string{"operate"},
+			wantErr:     "wrapped: operate error",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(initializedGameObj.DeepCopy()).
+				Build()
+
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			m := mocks.NewMockController(mctrl)
+			m.EXPECT().Default(gomock.Any(), gomock.Any())
+			m.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(nil)
+			m.EXPECT().UpdateStatus(gomock.Any(), gomock.Any())
+			if tc.wantOperate {
+				m.EXPECT().Operate(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+						calls = append(calls, "operate")
+						return ctrl.Result{}, tc.operateErr
+					})
+			}
+
+			cr := &CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, m, &tdv1alpha1.Game{},
+				WithScheme(scheme),
+				WithClient(cli),
+				WithMiddleware(tc.middlewares...),
+			))
+
+			result, err := cr.Reconcile(context.Background(), ctrl.Request{NamespacedName: gameNamespacedName})
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tc.wantResult, result)
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+}
+
+func TestReconcilePanicRecovery(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	initializedGameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{DefaultInitCondition},
+		},
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(initializedGameObj).
+		Build()
+
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	m := mocks.NewMockController(mctrl)
+	m.EXPECT().Default(gomock.Any(), gomock.Any())
+	m.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(nil)
+	m.EXPECT().UpdateStatus(gomock.Any(), gomock.Any())
+	m.EXPECT().Operate(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			panic("boom")
+		})
+
+	recorder := record.NewFakeRecorder(1)
+	cr := &CompositeReconciler{}
+	assert.Nil(t, cr.Init(nil, m, &tdv1alpha1.Game{},
+		WithName("panic-test"),
+		WithScheme(scheme),
+		WithClient(cli),
+		WithEventRecorder(recorder),
+		WithPanicRecovery(),
+	))
+
+	_, err := cr.Reconcile(context.Background(), ctrl.Request{NamespacedName: gameNamespacedName})
+	assert.True(t, tkctrl.IsPanic(err), "expected panic error, got: %v", err)
+	assert.Contains(t, <-recorder.Events, ReasonOperatePanic)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Panics.WithLabelValues(metrics.ComponentReconciler, "panic-test")))
+}
+
+// forgetController is a mock controller that records the forgotten objects.
+type forgetController struct {
+	*mocks.MockController
+	forgotten []types.UID
+}
+
+var _ Forgetter = &forgetController{}
+
+func (f *forgetController) Forget(ctx context.Context, obj client.Object) {
+	f.forgotten = append(f.forgotten, obj.GetUID())
+}
+
+func TestReconcileForget(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	game := func(uid types.UID) *tdv1alpha1.Game {
+		return &tdv1alpha1.Game{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-game",
+				Namespace: "test-ns",
+				UID:       uid,
+			},
+			Status: tdv1alpha1.GameStatus{
+				Conditions: []metav1.Condition{DefaultInitCondition},
+			},
+		}
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(game("uid-1")).
+		Build()
+
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	m := mocks.NewMockController(mctrl)
+	m.EXPECT().Default(gomock.Any(), gomock.Any()).AnyTimes()
+	m.EXPECT().Validate(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	m.EXPECT().UpdateStatus(gomock.Any(), gomock.Any()).AnyTimes()
+	m.EXPECT().Operate(gomock.Any(), gomock.Any()).AnyTimes()
+
+	fc := &forgetController{MockController: m}
+	cr := &CompositeReconciler{}
+	assert.Nil(t, cr.Init(nil, fc, &tdv1alpha1.Game{},
+		WithScheme(scheme),
+		WithClient(cli),
+	))
+
+	reconcile := func() {
+		_, err := cr.Reconcile(context.Background(), ctrl.Request{NamespacedName: gameNamespacedName})
+		assert.Nil(t, err)
+	}
+
+	reconcile()
+	assert.Empty(t, fc.forgotten)
+
+	// The previous object is forgotten when it's recreated with the same
+	// name.
+	assert.Nil(t, cli.Delete(context.Background(), game("uid-1")))
+	assert.Nil(t, cli.Create(context.Background(), game("uid-2")))
+	reconcile()
+	assert.Equal(t, []types.UID{"uid-1"}, fc.forgotten)
+
+	// The object is forgotten once it's not found, only once.
+	assert.Nil(t, cli.Delete(context.Background(), game("uid-2")))
+	reconcile()
+	reconcile()
+	assert.Equal(t, []types.UID{"uid-1", "uid-2"}, fc.forgotten)
+}
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tkctrl "github.com/ondat/operator-toolkit/controller"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
+)
+
+// ReasonOperatePanic is the reason of the Warning event recorded on the
+// parent object when its Operate panics.
+const ReasonOperatePanic = "OperatePanic"
+
+// ReconcileFunc is a step of the reconciliation of an object, like the
+// Controller Operate.
+type ReconcileFunc func(ctx context.Context, obj client.Object) (ctrl.Result, error)
+
+// Middleware wraps a ReconcileFunc with another ReconcileFunc, to run code
+// before and after the next ReconcileFunc, or to skip it. It's used to add
+// cross-cutting concerns to the reconciliation, like audit logging, feature
+// gating, rate limiting or panic recovery.
+type Middleware func(next ReconcileFunc) ReconcileFunc
+
+// chain wraps the given ReconcileFunc with the middlewares. The first
+// middleware is the outermost one and runs first.
+func chain(fn ReconcileFunc, middlewares ...Middleware) ReconcileFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		fn = middlewares[i](fn)
+	}
+	return fn
+}
+
+// recoverPanic is a Middleware that recovers from a panic in the next
+// ReconcileFunc and returns it as a controller.PanicError, with a Warning
+// event on the object.
+func (c *CompositeReconciler) recoverPanic(next ReconcileFunc) ReconcileFunc {
+	return func(ctx context.Context, obj client.Object) (result ctrl.Result, reterr error) {
+		defer func() {
+			if p := recover(); p != nil {
+				perr := tkctrl.NewPanicError(p)
+				_, span, log := c.inst.Start(ctx, "recoverPanic")
+				defer span.End()
+
+				tkctrl.RecordPanic(span, metrics.ComponentReconciler, c.name, perr)
+				log.Error(perr, "operate panicked", "stack", perr.Stack)
+				c.event(obj, eventv1.K8sEventTypeWarning, ReasonOperatePanic, "Operate panicked: %v", perr.Value)
+
+				result = ctrl.Result{}
+				reterr = fmt.Errorf("operate: %w", perr)
+			}
+		}()
+		return next(ctx, obj)
+	}
+}
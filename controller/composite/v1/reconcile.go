@@ -3,15 +3,22 @@ package v1
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/ondat/operator-toolkit/conditions"
+	"github.com/ondat/operator-toolkit/constant"
 	tkctrl "github.com/ondat/operator-toolkit/controller"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
 	"github.com/ondat/operator-toolkit/object"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 // Reconcile implements the composite controller reconciliation.
@@ -21,16 +28,29 @@ func (c *CompositeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	start := time.Now()
 	defer tkctrl.LogReconcileFinish(log, "reconciliation finished", start, &result, &reterr)
+	defer metrics.ObserveReconcile("composite", c.name, start, &result, &reterr)
 
 	controller := c.ctrlr
 
 	// Get an instance of the target object.
 	instance := c.prototype.DeepCopyObject().(client.Object)
 	if getErr := c.client.Get(ctx, req.NamespacedName, instance); getErr != nil {
+		if apierrors.IsNotFound(getErr) {
+			if uid, ok := c.uids.LoadAndDelete(req.NamespacedName); ok {
+				c.forget(ctx, req.NamespacedName, uid.(types.UID))
+			}
+		}
 		reterr = client.IgnoreNotFound(getErr)
 		return
 	}
 
+	// Forget the previous object with the same name if it was deleted and
+	// recreated in the meantime.
+	if uid, ok := c.uids.Load(req.NamespacedName); ok && uid.(types.UID) != instance.GetUID() {
+		c.forget(ctx, req.NamespacedName, uid.(types.UID))
+	}
+	c.uids.Store(req.NamespacedName, instance.GetUID())
+
 	// Add defaults to the primary object instance.
 	span.AddEvent("Populate defaults")
 	controller.Default(ctx, instance)
@@ -68,7 +88,7 @@ func (c *CompositeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 
 		// Update the object status in the API.
-		if updateErr := c.client.Status().Update(ctx, instance); updateErr != nil {
+		if updateErr := c.writeStatus(ctx, oldInstance, instance); updateErr != nil {
 			log.Error(updateErr, "failed to update initialized object")
 		}
 		span.AddEvent("Updated object status")
@@ -106,6 +126,10 @@ func (c *CompositeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return
 		}
 
+		// Set the standard conditions based on the result of the
+		// reconciliation.
+		c.setConditions(instance, result, reterr)
+
 		span.AddEvent("Checking for status change")
 
 		// Compare the old instance status with the updated instance status
@@ -118,7 +142,7 @@ func (c *CompositeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if changed {
 			span.AddEvent("Found status change, updating object")
 			// ?: Should patch status only if reterr is nil?
-			if statusErr := c.client.Status().Update(ctx, instance); statusErr != nil {
+			if statusErr := c.writeStatus(ctx, oldInstance, instance); statusErr != nil {
 				reterr = kerrors.NewAggregate([]error{reterr, fmt.Errorf("error while patching status: %v", statusErr)})
 			}
 		} else {
@@ -145,16 +169,134 @@ func (c *CompositeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
+	// Skip the operation if the current generation of the object has already
+	// been reconciled successfully.
+	if c.skipUnchangedGeneration && c.generationReconciled(instance) {
+		span.AddEvent("Generation already reconciled, skipping Operate")
+		return
+	}
+
 	// Run the operation.
 	span.AddEvent("Run Operate")
-	result, reterr = controller.Operate(ctx, instance)
+	result, reterr = chain(controller.Operate, c.middlewares...)(ctx, instance)
 	if reterr != nil {
 		log.Error(reterr, "failed to finish Operation")
 	}
 
+	// Record the observed generation of the object.
+	c.recordGeneration(ctx, instance, result, reterr)
+
 	return
 }
 
+// forget releases the state of the deleted object with the given key and UID
+// and tells the Controller, if it implements Forgetter, to forget it.
+func (c *CompositeReconciler) forget(ctx context.Context, key types.NamespacedName, uid types.UID) {
+	c.reconciledGenerations.Delete(uid)
+
+	f, ok := c.ctrlr.(Forgetter)
+	if !ok {
+		return
+	}
+	ctx, span, _ := c.inst.Start(ctx, "forget")
+	defer span.End()
+
+	obj := c.prototype.DeepCopyObject().(client.Object)
+	obj.SetNamespace(key.Namespace)
+	obj.SetName(key.Name)
+	obj.SetUID(uid)
+	f.Forget(ctx, obj)
+}
+
+// recordGeneration records the generation of the object as its observed
+// generation in the object status if Operate succeeded. The generation is
+// also remembered as successfully reconciled if no requeue was requested.
+func (c *CompositeReconciler) recordGeneration(ctx context.Context, obj client.Object, result ctrl.Result, err error) {
+	_, span, log := c.inst.Start(ctx, "recordGeneration")
+	defer span.End()
+
+	if err != nil {
+		c.reconciledGenerations.Delete(obj.GetUID())
+		return
+	}
+
+	if setErr := object.SetObservedGeneration(c.scheme, obj, obj.GetGeneration()); setErr != nil {
+		span.RecordError(setErr)
+		log.Error(setErr, "failed to set observed generation")
+	}
+
+	if result.IsZero() {
+		c.reconciledGenerations.Store(obj.GetUID(), reconciledGeneration{
+			generation: obj.GetGeneration(),
+			control:    controlAnnotations(obj),
+		})
+	} else {
+		c.reconciledGenerations.Delete(obj.GetUID())
+	}
+}
+
+// generationReconciled returns true if the generation of the object is the
+// same as the observed generation in its status and the last Operate on the
+// generation, with the same control annotations, was successful.
+func (c *CompositeReconciler) generationReconciled(obj client.Object) bool {
+	observed, found, err := object.GetObservedGeneration(c.scheme, obj)
+	if err != nil || !found || observed != obj.GetGeneration() {
+		return false
+	}
+
+	val, ok := c.reconciledGenerations.Load(obj.GetUID())
+	if !ok {
+		return false
+	}
+	rg := val.(reconciledGeneration)
+	return rg.generation == obj.GetGeneration() && rg.control == controlAnnotations(obj)
+}
+
+// reconciledGeneration is a generation of an object for which Operate was
+// successful, with the control annotations of the object at that time.
+type reconciledGeneration struct {
+	generation int64
+	control    string
+}
+
+// controlAnnotations returns the annotations of the object with the
+// operator-toolkit prefix, sorted and joined in a string. Changing them
+// doesn't change the object generation, but they control the execution of
+// Operate, like forcing the rerun of an operand.
+func controlAnnotations(obj client.Object) string {
+	control := []string{}
+	for k, v := range obj.GetAnnotations() {
+		if strings.HasPrefix(k, constant.AnnotationPrefix) {
+			control = append(control, k+"="+v)
+		}
+	}
+	sort.Strings(control)
+	return strings.Join(control, "\n")
+}
+
+// setConditions sets the standard conditions on the object if condition
+// management is enabled and the object supports conditions.
+func (c *CompositeReconciler) setConditions(obj client.Object, result ctrl.Result, err error) {
+	if !c.manageConditions {
+		return
+	}
+	setter, ok := obj.(conditions.Setter)
+	if !ok {
+		return
+	}
+
+	requeue := result.Requeue || result.RequeueAfter > 0
+	if !obj.GetDeletionTimestamp().IsZero() {
+		if err != nil {
+			conditions.SetReconcileConditions(setter, requeue, err, conditions.ReasonCleanupFailed)
+			return
+		}
+		conditions.MarkDeleting(setter)
+		return
+	}
+	conditions.SetReconcileConditions(setter, requeue, err, conditions.ReasonOperateFailed, c.summaryConditions...)
+}
+
 // cleanupHandler checks if the target object is marked for deletion. If not,
 // it ensures that a finalizer is added to the target object. If an object is
 // marked for deletion, it runs the custom cleanup functions and returns the
@@ -168,10 +310,9 @@ func (c *CompositeReconciler) cleanupHandler(ctx context.Context, obj client.Obj
 
 	if obj.GetDeletionTimestamp().IsZero() {
 		span.AddEvent("No delete timestamp")
-		// If the object does not contain finalizer, add it.
-		if !controllerutil.ContainsFinalizer(obj, c.finalizerName) {
-			span.AddEvent("Finalizer not found, updating object to add finalizer")
-			controllerutil.AddFinalizer(obj, c.finalizerName)
+		// If the object does not contain the finalizers, add them.
+		if c.ensureFinalizers(obj) {
+			span.AddEvent("Finalizers changed, updating object")
 			if reterr = c.client.Update(ctx, obj); reterr != nil {
 				log.Error(reterr, "failed to add finalizer")
 			} else {
@@ -186,23 +327,24 @@ func (c *CompositeReconciler) cleanupHandler(ctx context.Context, obj client.Obj
 		span.AddEvent("Delete timestamp found")
 		delEnabled = true
 
+		// Run the cleanup stages if configured.
+		if len(c.cleanupStages) > 0 {
+			updated, result, reterr = c.runCleanupStages(ctx, obj)
+			return
+		}
+
 		// Perform cleanup if finalizer is found.
 		if contains(obj.GetFinalizers(), c.finalizerName) {
 			span.AddEvent("Finalizer found, run cleanup")
 			result, reterr = c.ctrlr.Cleanup(ctx, obj)
 			if reterr != nil {
 				log.Error(reterr, "failed to cleanup")
-			} else {
-				// Cleanup successful, remove the finalizer.
+				reterr = c.cleanupFailed(ctx, obj, c.finalizerName, reterr)
+			}
+			if reterr == nil {
+				// Cleanup successful or forced, remove the finalizer.
 				span.AddEvent("Cleanup completed, remove finalizer")
-				controllerutil.RemoveFinalizer(obj, c.finalizerName)
-				if reterr = c.client.Update(ctx, obj); reterr != nil {
-					log.Error(reterr, "failed to remove finalizer")
-				} else {
-					// Mark API object update.
-					updated = true
-					result = ctrl.Result{Requeue: true}
-				}
+				updated, result, reterr = c.removeFinalizer(ctx, obj, c.finalizerName)
 			}
 		} else {
 			span.AddEvent("Finalizer not found, no-op")
@@ -211,6 +353,49 @@ func (c *CompositeReconciler) cleanupHandler(ctx context.Context, obj client.Obj
 	return
 }
 
+// cleanupFailed handles a failed cleanup of an object. It returns nil if the
+// given finalizer must be removed regardless of the failure, because of the
+// force-delete annotation or the forced removal after the cleanup timeout.
+// Otherwise, it returns the cleanup error, with the CleanupTimedOut condition
+// reason once the cleanup timeout has passed.
+func (c *CompositeReconciler) cleanupFailed(ctx context.Context, obj client.Object, finalizer string, err error) error {
+	_, span, log := c.inst.Start(ctx, "cleanupFailed")
+	defer span.End()
+
+	if obj.GetAnnotations()[AnnotationForceDelete] == "true" {
+		span.AddEvent("Force delete annotation found, ignoring cleanup failure")
+		log.Info("removing finalizer of failed cleanup", "annotation", AnnotationForceDelete)
+		c.event(obj, eventv1.K8sEventTypeWarning, ReasonFinalizerForceRemoved,
+			"Removing finalizer %q because of the %s annotation, cleanup failed: %v", finalizer, AnnotationForceDelete, err)
+		return nil
+	}
+
+	if c.cleanupTimeout <= 0 || time.Since(obj.GetDeletionTimestamp().Time) < c.cleanupTimeout {
+		return err
+	}
+
+	if c.forceFinalizerRemoval {
+		span.AddEvent("Cleanup timed out, ignoring cleanup failure")
+		log.Info("removing finalizer of timed out cleanup", "timeout", c.cleanupTimeout)
+		c.event(obj, eventv1.K8sEventTypeWarning, ReasonFinalizerForceRemoved,
+			"Removing finalizer %q, cleanup not completed within %s: %v", finalizer, c.cleanupTimeout, err)
+		return nil
+	}
+
+	span.AddEvent("Cleanup timed out")
+	c.event(obj, eventv1.K8sEventTypeWarning, ReasonCleanupTimedOut,
+		"Cleanup not completed within %s: %v", c.cleanupTimeout, err)
+	return conditions.WithReason(fmt.Errorf("cleanup not completed within %s: %w", c.cleanupTimeout, err), conditions.ReasonCleanupTimedOut)
+}
+
+// event records an event on the object if an event recorder is configured.
+func (c *CompositeReconciler) event(obj client.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil {
+		return
+	}
+	c.recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
 func contains(slice []string, s string) bool {
 	for _, element := range slice {
 		if element == s {
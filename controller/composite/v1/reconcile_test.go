@@ -10,9 +10,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"github.com/ondat/operator-toolkit/conditions"
 	"github.com/ondat/operator-toolkit/controller/composite/v1/mocks"
 	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
 )
@@ -149,3 +151,154 @@ func TestCleanupHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestCleanupHandlerTimeout(t *testing.T) {
+	// Create a scheme with testdata scheme info.
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	finalizerName := "test-finalizer"
+	someErr := errors.New("some cleanup error")
+
+	newGame := func(deletedSince time.Duration, annotations map[string]string) *tdv1alpha1.Game {
+		return &tdv1alpha1.Game{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "my-game",
+				Namespace:         "default",
+				Annotations:       annotations,
+				Finalizers:        []string{finalizerName},
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-deletedSince)},
+			},
+		}
+	}
+
+	cases := []struct {
+		name           string
+		obj            *tdv1alpha1.Game
+		opts           []CompositeReconcilerOption
+		cleanupErr     error
+		wantFinalizers []string
+		wantUpdated    bool
+		wantErr        bool
+		wantReason     string
+		wantEvent      string
+	}{
+		{
+			name:           "cleanup failed within timeout",
+			obj:            newGame(time.Minute, nil),
+			opts:           []CompositeReconcilerOption{WithCleanupTimeout(time.Hour)},
+			cleanupErr:     someErr,
+			wantFinalizers: []string{finalizerName},
+			wantErr:        true,
+			wantReason:     conditions.ReasonCleanupFailed,
+		},
+		{
+			name:           "cleanup failed after timeout",
+			obj:            newGame(2*time.Hour, nil),
+			opts:           []CompositeReconcilerOption{WithCleanupTimeout(time.Hour)},
+			cleanupErr:     someErr,
+			wantFinalizers: []string{finalizerName},
+			wantErr:        true,
+			wantReason:     conditions.ReasonCleanupTimedOut,
+			wantEvent:      ReasonCleanupTimedOut,
+		},
+		{
+			name:           "cleanup failed without timeout",
+			obj:            newGame(24*time.Hour, nil),
+			opts:           []CompositeReconcilerOption{WithForceFinalizerRemoval()},
+			cleanupErr:     someErr,
+			wantFinalizers: []string{finalizerName},
+			wantErr:        true,
+			wantReason:     conditions.ReasonCleanupFailed,
+		},
+		{
+			name:           "force finalizer removal after timeout",
+			obj:            newGame(2*time.Hour, nil),
+			opts:           []CompositeReconcilerOption{WithCleanupTimeout(time.Hour), WithForceFinalizerRemoval()},
+			cleanupErr:     someErr,
+			wantFinalizers: []string{},
+			wantUpdated:    true,
+			wantEvent:      ReasonFinalizerForceRemoved,
+		},
+		{
+			name:           "force finalizer removal within timeout",
+			obj:            newGame(time.Minute, nil),
+			opts:           []CompositeReconcilerOption{WithCleanupTimeout(time.Hour), WithForceFinalizerRemoval()},
+			cleanupErr:     someErr,
+			wantFinalizers: []string{finalizerName},
+			wantErr:        true,
+			wantReason:     conditions.ReasonCleanupFailed,
+		},
+		{
+			name:           "force delete annotation",
+			obj:            newGame(time.Minute, map[string]string{AnnotationForceDelete: "true"}),
+			cleanupErr:     someErr,
+			wantFinalizers: []string{},
+			wantUpdated:    true,
+			wantEvent:      ReasonFinalizerForceRemoved,
+		},
+		{
+			name:           "force delete annotation not true",
+			obj:            newGame(time.Minute, map[string]string{AnnotationForceDelete: "false"}),
+			cleanupErr:     someErr,
+			wantFinalizers: []string{finalizerName},
+			wantErr:        true,
+			wantReason:     conditions.ReasonCleanupFailed,
+		},
+		{
+			name:           "successful cleanup after timeout",
+			obj:            newGame(2*time.Hour, nil),
+			opts:           []CompositeReconcilerOption{WithCleanupTimeout(time.Hour)},
+			wantFinalizers: []string{},
+			wantUpdated:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithObjects(tc.obj).
+				WithScheme(scheme).
+				Build()
+
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			m := mocks.NewMockController(mctrl)
+			m.EXPECT().Cleanup(gomock.Any(), gomock.Any()).Return(ctrl.Result{}, tc.cleanupErr)
+
+			recorder := record.NewFakeRecorder(10)
+			opts := append([]CompositeReconcilerOption{
+				WithScheme(scheme),
+				WithFinalizer(finalizerName),
+				WithClient(cli),
+				WithEventRecorder(recorder),
+			}, tc.opts...)
+
+			cr := CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, m, nil, opts...))
+
+			delEnabled, updated, _, err := cr.cleanupHandler(context.Background(), tc.obj)
+			assert.True(t, delEnabled)
+			assert.Equal(t, tc.wantUpdated, updated, "updated result")
+			assert.Equal(t, tc.wantFinalizers, tc.obj.GetFinalizers(), "finalizers after cleanupHandler call")
+			if tc.wantErr {
+				assert.ErrorIs(t, err, someErr)
+				assert.Equal(t, tc.wantReason, conditions.ReasonForError(err, conditions.ReasonCleanupFailed))
+			} else {
+				assert.Nil(t, err)
+			}
+
+			close(recorder.Events)
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			if tc.wantEvent == "" {
+				assert.Empty(t, events)
+			} else if assert.Len(t, events, 1) {
+				assert.Contains(t, events[0], "Warning "+tc.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/ondat/operator-toolkit/constant"
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// writeStatus writes the status of the given object to the API server using
+// the configured StatusWriteMode. The old object is the object as fetched at
+// the beginning of the reconciliation. It's used as the base for computing
+// the patches.
+func (c *CompositeReconciler) writeStatus(ctx context.Context, oldObj runtime.Object, obj client.Object) error {
+	switch c.statusWriteMode {
+	case StatusUpdate:
+		return c.client.Status().Update(ctx, obj)
+	case StatusMergePatch:
+		base, ok := oldObj.(client.Object)
+		if !ok {
+			return fmt.Errorf("failed to convert %T to client.Object", oldObj)
+		}
+		return c.client.Status().Patch(ctx, obj, client.MergeFrom(base))
+	case StatusOptimisticLockPatch:
+		base, ok := oldObj.(client.Object)
+		if !ok {
+			return fmt.Errorf("failed to convert %T to client.Object", oldObj)
+		}
+		return c.client.Status().Patch(ctx, obj, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	case StatusServerSideApply:
+		applyObj, err := statusApplyObject(c.scheme, obj)
+		if err != nil {
+			return err
+		}
+		force := true
+		if err := c.client.Status().Patch(ctx, applyObj, client.Apply, &client.SubResourcePatchOptions{
+			PatchOptions: client.PatchOptions{
+				FieldManager: c.fieldManager(),
+				Force:        &force,
+			},
+		}); err != nil {
+			return err
+		}
+		// Like with the other write modes, the object is updated with the
+		// response of the API server, returned in the apply object.
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applyObj.Object, obj); err != nil {
+			return fmt.Errorf("failed to convert the applied object: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown status write mode: %v", c.statusWriteMode)
+	}
+}
+
+// fieldManager returns the name of the field manager used for server-side
+// apply. The reconciler name is used when available.
+func (c *CompositeReconciler) fieldManager() string {
+	if c.name != "" {
+		return c.name
+	}
+	return constant.LibraryName
+}
+
+// statusApplyObject returns an apply configuration of the given object that
+// contains only the identity of the object and its status. Sending the whole
+// object in a server-side apply request would make the reconciler own all
+// the fields of the object.
+func statusApplyObject(scheme *runtime.Scheme, obj client.Object) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVK of the object: %w", err)
+	}
+
+	u, err := object.GetUnstructuredObject(scheme, obj)
+	if err != nil {
+		return nil, err
+	}
+	status, err := object.GetObjectStatus(u.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	applyObj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	applyObj.SetGroupVersionKind(gvk)
+	applyObj.SetName(obj.GetName())
+	applyObj.SetNamespace(obj.GetNamespace())
+	if err := unstructured.SetNestedField(applyObj.Object, status, "status"); err != nil {
+		return nil, fmt.Errorf("failed to set status in the apply object: %w", err)
+	}
+
+	return applyObj, nil
+}
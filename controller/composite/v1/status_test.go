@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+func TestWriteStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+	}
+
+	newCondition := metav1.Condition{
+		Type:               "Ready",
+		Status:             metav1.ConditionTrue,
+		Reason:             "Ready",
+		LastTransitionTime: metav1.Now(),
+	}
+
+	testcases := []struct {
+		name string
+		mode StatusWriteMode
+		// modifiedInBetween modifies the object in the API after the
+		// reconciler has fetched it.
+		modifiedInBetween bool
+		wantConflict      bool
+	}{
+		{
+			name: "update",
+			mode: StatusUpdate,
+		},
+		{
+			name:              "update with stale object",
+			mode:              StatusUpdate,
+			modifiedInBetween: true,
+			wantConflict:      true,
+		},
+		{
+			name:              "merge patch with stale object",
+			mode:              StatusMergePatch,
+			modifiedInBetween: true,
+		},
+		{
+			name: "optimistic lock patch",
+			mode: StatusOptimisticLockPatch,
+		},
+		{
+			name:              "optimistic lock patch with stale object",
+			mode:              StatusOptimisticLockPatch,
+			modifiedInBetween: true,
+			wantConflict:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(gameObj.DeepCopy()).
+				Build()
+
+			cr := &CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, nil, &tdv1alpha1.Game{},
+				WithScheme(scheme),
+				WithClient(cli),
+				WithStatusWriteMode(tc.mode),
+			))
+
+			ctx := context.Background()
+			instance := &tdv1alpha1.Game{}
+			assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(gameObj), instance))
+			oldInstance := instance.DeepCopyObject()
+
+			if tc.modifiedInBetween {
+				other := instance.DeepCopy()
+				other.Spec.Foo = "bar"
+				assert.Nil(t, cli.Update(ctx, other))
+			}
+
+			instance.Status.Conditions = []metav1.Condition{newCondition}
+			err := cr.writeStatus(ctx, oldInstance, instance)
+			if tc.wantConflict {
+				assert.True(t, apierrors.IsConflict(err), "expected conflict error, got: %v", err)
+				return
+			}
+			assert.Nil(t, err)
+
+			got := &tdv1alpha1.Game{}
+			assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(gameObj), got))
+			assert.Len(t, got.Status.Conditions, 1)
+			assert.Equal(t, "Ready", got.Status.Conditions[0].Type)
+		})
+	}
+}
+
+// applyClient emulates the server-side apply of the status, which the fake
+// client doesn't support, by updating the status of the live object with the
+// status of the apply object.
+type applyClient struct {
+	client.Client
+}
+
+func (c *applyClient) Status() client.SubResourceWriter {
+	return &applyStatusWriter{SubResourceWriter: c.Client.Status(), client: c.Client}
+}
+
+type applyStatusWriter struct {
+	client.SubResourceWriter
+	client client.Client
+}
+
+func (w *applyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if patch != client.Apply {
+		return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+	}
+	applyObj := obj.(*unstructured.Unstructured)
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(applyObj.GroupVersionKind())
+	if err := w.client.Get(ctx, client.ObjectKeyFromObject(applyObj), live); err != nil {
+		return err
+	}
+	live.Object["status"] = applyObj.Object["status"]
+	if err := w.client.Status().Update(ctx, live); err != nil {
+		return err
+	}
+	applyObj.Object = live.Object
+	return nil
+}
+
+func TestWriteStatusServerSideApply(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+	}
+	cli := &applyClient{Client: fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(gameObj).
+		Build()}
+
+	cr := &CompositeReconciler{}
+	assert.Nil(t, cr.Init(nil, nil, &tdv1alpha1.Game{},
+		WithScheme(scheme),
+		WithClient(cli),
+		WithStatusWriteMode(StatusServerSideApply),
+	))
+
+	ctx := context.Background()
+	instance := &tdv1alpha1.Game{}
+	assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(gameObj), instance))
+	oldInstance := instance.DeepCopyObject()
+
+	// The object is modified in the API after the reconciler has fetched
+	// it.
+	other := instance.DeepCopy()
+	other.Spec.Foo = "bar"
+	assert.Nil(t, cli.Update(ctx, other))
+
+	instance.Status.Conditions = []metav1.Condition{DefaultInitCondition}
+	assert.Nil(t, cr.writeStatus(ctx, oldInstance, instance))
+
+	// The instance is updated with the applied object.
+	got := &tdv1alpha1.Game{}
+	assert.Nil(t, cli.Get(ctx, client.ObjectKeyFromObject(gameObj), got))
+	assert.Equal(t, got.GetResourceVersion(), instance.GetResourceVersion())
+	assert.Equal(t, "bar", instance.Spec.Foo)
+	assert.Len(t, instance.Status.Conditions, 1)
+	assert.Equal(t, got.Status, instance.Status)
+}
+
+func TestStatusApplyObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-game",
+			Namespace:       "test-ns",
+			ResourceVersion: "10",
+			Labels:          map[string]string{"foo": "bar"},
+		},
+		Spec: tdv1alpha1.GameSpec{Foo: "foo"},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{DefaultInitCondition},
+		},
+	}
+
+	applyObj, err := statusApplyObject(scheme, gameObj)
+	assert.Nil(t, err)
+
+	assert.Equal(t, tdv1alpha1.GroupVersion.String(), applyObj.GetAPIVersion())
+	assert.Equal(t, "Game", applyObj.GetKind())
+	assert.Equal(t, "test-game", applyObj.GetName())
+	assert.Equal(t, "test-ns", applyObj.GetNamespace())
+	assert.Empty(t, applyObj.GetResourceVersion(), "resource version must not be set")
+	assert.Empty(t, applyObj.GetLabels(), "labels must not be set")
+
+	_, found, _ := unstructured.NestedFieldNoCopy(applyObj.Object, "spec")
+	assert.False(t, found, "spec must not be set")
+
+	conditions, found, err := unstructured.NestedSlice(applyObj.Object, "status", "conditions")
+	assert.Nil(t, err)
+	assert.True(t, found)
+	assert.Len(t, conditions, 1)
+}
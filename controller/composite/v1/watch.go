@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// WatchOption is used to configure the watches of the child objects.
+type WatchOption func(*watchConfig)
+
+// watchConfig is the configuration of the watches of the child objects.
+type watchConfig struct {
+	// ownerAnnotationKinds are the kinds watched through the owner
+	// annotations regardless of their scope.
+	ownerAnnotationKinds map[schema.GroupKind]bool
+}
+
+// WithOwnerAnnotationKinds sets the kinds of the child objects that are
+// watched through their owner annotations instead of their owner reference,
+// usually because they're created in another namespace than the parent.
+func WithOwnerAnnotationKinds(kinds ...schema.GroupKind) WatchOption {
+	return func(c *watchConfig) {
+		for _, gk := range kinds {
+			c.ownerAnnotationKinds[gk] = true
+		}
+	}
+}
+
+// childWatch is the watch of the child objects of a kind.
+type childWatch struct {
+	// object is the prototype of the watched objects.
+	object client.Object
+	// byOwnerAnnotation tells if the objects are mapped to their parent with
+	// the owner annotations, or else with the owner reference.
+	byOwnerAnnotation bool
+}
+
+// WatchChildren adds the watches of the child objects of the given kinds,
+// usually the managed kinds of the operator, to the controller builder of the
+// parent objects. A change in a child object triggers the reconciliation of
+// its parent object. The child objects are mapped to their parent with their
+// controller owner reference, except the cluster-scoped child objects of a
+// namespaced parent and the kinds set with WithOwnerAnnotationKinds, which
+// are mapped with their owner annotations, see object.SetOwnerAnnotations.
+func WatchChildren(bldr *builder.Builder, mgr ctrl.Manager, parent client.Object, kinds []schema.GroupVersionKind, opts ...WatchOption) (*builder.Builder, error) {
+	parentGVK, err := apiutil.GVKForObject(parent, mgr.GetScheme())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVK of the parent object: %w", err)
+	}
+
+	watches, err := childWatches(mgr.GetScheme(), mgr.GetRESTMapper(), parentGVK, kinds, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range watches {
+		if w.byOwnerAnnotation {
+			bldr = bldr.Watches(&source.Kind{Type: w.object}, EnqueueRequestForOwnerAnnotation(parentGVK.GroupKind()))
+		} else {
+			bldr = bldr.Owns(w.object)
+		}
+	}
+	return bldr, nil
+}
+
+// childWatches returns the watches of the child objects of the given kinds
+// of the parent objects of the given kind.
+func childWatches(scheme *runtime.Scheme, mapper meta.RESTMapper, parentGVK schema.GroupVersionKind, kinds []schema.GroupVersionKind, opts ...WatchOption) ([]childWatch, error) {
+	cfg := &watchConfig{ownerAnnotationKinds: map[schema.GroupKind]bool{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	parentNamespaced, err := isNamespaced(mapper, parentGVK)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[schema.GroupVersionKind]bool{}
+	watches := []childWatch{}
+	for _, gvk := range kinds {
+		if seen[gvk] {
+			continue
+		}
+		seen[gvk] = true
+
+		byOwnerAnnotation := cfg.ownerAnnotationKinds[gvk.GroupKind()]
+		if !byOwnerAnnotation && parentNamespaced {
+			// Cluster-scoped objects can't be owned by namespaced objects.
+			namespaced, err := isNamespaced(mapper, gvk)
+			if err != nil {
+				return nil, err
+			}
+			byOwnerAnnotation = !namespaced
+		}
+
+		watches = append(watches, childWatch{
+			object:            object.NewObject(scheme, gvk),
+			byOwnerAnnotation: byOwnerAnnotation,
+		})
+	}
+	return watches, nil
+}
+
+// isNamespaced tells if the objects of the given kind are namespaced.
+func isNamespaced(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, fmt.Errorf("failed to get REST mapping of %s: %w", gvk, err)
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// EnqueueRequestForOwnerAnnotation returns an event handler that enqueues a
+// request for the owner of the object in the event, from the owner
+// annotations of the object, if the owner is of the given kind.
+func EnqueueRequestForOwnerAnnotation(ownerKind schema.GroupKind) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		kind, owner, ok := object.OwnerFromAnnotations(obj)
+		if !ok || kind != ownerKind {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: owner}}
+	})
+}
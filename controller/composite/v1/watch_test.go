@@ -0,0 +1,174 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/ondat/operator-toolkit/object"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+func TestChildWatches(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, scheme.AddToScheme(s))
+	assert.Nil(t, tdv1alpha1.AddToScheme(s))
+
+	gameGVK := tdv1alpha1.GroupVersion.WithKind("Game")
+	clusterGameGVK := tdv1alpha1.GroupVersion.WithKind("ClusterGame")
+	configMapGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	clusterRoleGVK := rbacv1.SchemeGroupVersion.WithKind("ClusterRole")
+	widgetGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(gameGVK, meta.RESTScopeNamespace)
+	mapper.Add(clusterGameGVK, meta.RESTScopeRoot)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	mapper.Add(clusterRoleGVK, meta.RESTScopeRoot)
+	mapper.Add(widgetGVK, meta.RESTScopeNamespace)
+
+	type watch struct {
+		gvk               schema.GroupVersionKind
+		byOwnerAnnotation bool
+	}
+
+	cases := []struct {
+		name        string
+		parent      schema.GroupVersionKind
+		kinds       []schema.GroupVersionKind
+		opts        []WatchOption
+		wantWatches []watch
+		wantErr     bool
+	}{
+		{
+			name:   "namespaced parent",
+			parent: gameGVK,
+			kinds:  []schema.GroupVersionKind{configMapGVK, clusterRoleGVK, configMapGVK},
+			wantWatches: []watch{
+				{gvk: configMapGVK},
+				{gvk: clusterRoleGVK, byOwnerAnnotation: true},
+			},
+		},
+		{
+			name:   "cluster-scoped parent",
+			parent: clusterGameGVK,
+			kinds:  []schema.GroupVersionKind{configMapGVK, clusterRoleGVK},
+			wantWatches: []watch{
+				{gvk: configMapGVK},
+				{gvk: clusterRoleGVK},
+			},
+		},
+		{
+			name:   "owner annotation kinds",
+			parent: gameGVK,
+			kinds:  []schema.GroupVersionKind{configMapGVK, widgetGVK},
+			opts:   []WatchOption{WithOwnerAnnotationKinds(configMapGVK.GroupKind())},
+			wantWatches: []watch{
+				{gvk: configMapGVK, byOwnerAnnotation: true},
+				{gvk: widgetGVK},
+			},
+		},
+		{
+			name:    "unknown kind",
+			parent:  gameGVK,
+			kinds:   []schema.GroupVersionKind{{Group: "example.com", Version: "v1", Kind: "Unknown"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			watches, err := childWatches(s, mapper, tc.parent, tc.kinds, tc.opts...)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			got := []watch{}
+			for _, w := range watches {
+				gvk := w.object.GetObjectKind().GroupVersionKind()
+				if gvk.Empty() {
+					// Typed objects don't carry their GVK.
+					gvks, _, err := s.ObjectKinds(w.object)
+					assert.Nil(t, err)
+					gvk = gvks[0]
+				}
+				got = append(got, watch{gvk: gvk, byOwnerAnnotation: w.byOwnerAnnotation})
+			}
+			assert.Equal(t, tc.wantWatches, got)
+		})
+	}
+
+	// The kinds not registered in the scheme are watched as unstructured.
+	watches, err := childWatches(s, mapper, gameGVK, []schema.GroupVersionKind{widgetGVK})
+	assert.Nil(t, err)
+	assert.IsType(t, &unstructured.Unstructured{}, watches[0].object)
+}
+
+func TestEnqueueRequestForOwnerAnnotation(t *testing.T) {
+	gameKind := tdv1alpha1.GroupVersion.WithKind("Game").GroupKind()
+	owner := types.NamespacedName{Namespace: "default", Name: "my-game"}
+
+	annotated := func(kind schema.GroupKind) *rbacv1.ClusterRole {
+		obj := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+		object.SetOwnerAnnotations(obj, kind, owner)
+		return obj
+	}
+
+	cases := []struct {
+		name string
+		obj  *rbacv1.ClusterRole
+		want []reconcile.Request
+	}{
+		{
+			name: "owner of the kind",
+			obj:  annotated(gameKind),
+			want: []reconcile.Request{{NamespacedName: owner}},
+		},
+		{
+			name: "owner of another kind",
+			obj:  annotated(schema.GroupKind{Group: "example.com", Kind: "Widget"}),
+		},
+		{
+			name: "no owner annotations",
+			obj:  &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+
+			h := EnqueueRequestForOwnerAnnotation(gameKind)
+			h.Create(event.CreateEvent{Object: tc.obj}, q)
+
+			got := []reconcile.Request{}
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(reconcile.Request))
+				q.Done(item)
+			}
+			if tc.want == nil {
+				assert.Empty(t, got)
+			} else {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
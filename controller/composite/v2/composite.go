@@ -0,0 +1,138 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compositev1 "github.com/ondat/operator-toolkit/controller/composite/v1"
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// CompositeReconciler is the typed variant of the v1 CompositeReconciler, for
+// the parent objects of type T, which must be a pointer to a struct type.
+type CompositeReconciler[T client.Object] struct {
+	compositev1.CompositeReconciler
+}
+
+// Init initializes the CompositeReconciler with the given typed Controller
+// and the v1 CompositeReconciler options. The prototype of the reconciled
+// objects is a new object of type T.
+func (c *CompositeReconciler[T]) Init(mgr ctrl.Manager, ctrlr Controller[T], opts ...compositev1.CompositeReconcilerOption) error {
+	prototype, err := object.New[T]()
+	if err != nil {
+		return fmt.Errorf("invalid parent object type: %w", err)
+	}
+	return c.CompositeReconciler.Init(mgr, &controller[T]{ctrlr: ctrlr}, prototype, opts...)
+}
+
+// ReconcileFunc is the typed variant of the v1 ReconcileFunc.
+type ReconcileFunc[T client.Object] func(ctx context.Context, obj T) (ctrl.Result, error)
+
+// Middleware is the typed variant of the v1 Middleware.
+type Middleware[T client.Object] func(next ReconcileFunc[T]) ReconcileFunc[T]
+
+// WithMiddleware adds typed middlewares around the Controller Operate, see the
+// v1 WithMiddleware.
+func WithMiddleware[T client.Object](middlewares ...Middleware[T]) compositev1.CompositeReconcilerOption {
+	untyped := make([]compositev1.Middleware, 0, len(middlewares))
+	for _, mw := range middlewares {
+		untyped = append(untyped, untypedMiddleware(mw))
+	}
+	return compositev1.WithMiddleware(untyped...)
+}
+
+// untypedMiddleware converts a typed Middleware into a v1 Middleware.
+func untypedMiddleware[T client.Object](mw Middleware[T]) compositev1.Middleware {
+	return func(next compositev1.ReconcileFunc) compositev1.ReconcileFunc {
+		typed := mw(func(ctx context.Context, obj T) (ctrl.Result, error) {
+			return next(ctx, obj)
+		})
+		return func(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+			t, err := cast[T](obj)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			return typed(ctx, t)
+		}
+	}
+}
+
+// controller adapts a typed Controller to the v1 Controller interface.
+type controller[T client.Object] struct {
+	ctrlr Controller[T]
+}
+
+var _ compositev1.Controller = &controller[client.Object]{}
+var _ compositev1.Forgetter = &controller[client.Object]{}
+
+// cast returns the object as T. The reconciled objects are always of type T,
+// created from the prototype.
+func cast[T client.Object](obj client.Object) (T, error) {
+	t, ok := obj.(T)
+	if !ok {
+		return t, fmt.Errorf("unexpected object type %T", obj)
+	}
+	return t, nil
+}
+
+func (c *controller[T]) Default(ctx context.Context, obj client.Object) {
+	if t, err := cast[T](obj); err == nil {
+		c.ctrlr.Default(ctx, t)
+	}
+}
+
+func (c *controller[T]) Validate(ctx context.Context, obj client.Object) error {
+	t, err := cast[T](obj)
+	if err != nil {
+		return err
+	}
+	return c.ctrlr.Validate(ctx, t)
+}
+
+func (c *controller[T]) Initialize(ctx context.Context, obj client.Object, condn metav1.Condition) error {
+	t, err := cast[T](obj)
+	if err != nil {
+		return err
+	}
+	return c.ctrlr.Initialize(ctx, t, condn)
+}
+
+func (c *controller[T]) UpdateStatus(ctx context.Context, obj client.Object) error {
+	t, err := cast[T](obj)
+	if err != nil {
+		return err
+	}
+	return c.ctrlr.UpdateStatus(ctx, t)
+}
+
+func (c *controller[T]) Operate(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+	t, err := cast[T](obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return c.ctrlr.Operate(ctx, t)
+}
+
+func (c *controller[T]) Cleanup(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+	t, err := cast[T](obj)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return c.ctrlr.Cleanup(ctx, t)
+}
+
+// Forget implements the v1 Forgetter interface. The object is forgotten only
+// if the typed controller implements Forgetter.
+func (c *controller[T]) Forget(ctx context.Context, obj client.Object) {
+	f, ok := c.ctrlr.(Forgetter[T])
+	if !ok {
+		return
+	}
+	if t, err := cast[T](obj); err == nil {
+		f.Forget(ctx, t)
+	}
+}
@@ -0,0 +1,120 @@
+package v2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	compositev1 "github.com/ondat/operator-toolkit/controller/composite/v1"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+// gameController is a typed Controller that records the calls.
+type gameController struct {
+	calls []string
+}
+
+var _ Controller[*tdv1alpha1.Game] = &gameController{}
+
+func (g *gameController) Default(ctx context.Context, obj *tdv1alpha1.Game) {
+	g.calls = append(g.calls, "default "+obj.Name)
+}
+
+func (g *gameController) Validate(ctx context.Context, obj *tdv1alpha1.Game) error {
+	g.calls = append(g.calls, "validate "+obj.Name)
+	return nil
+}
+
+func (g *gameController) Initialize(ctx context.Context, obj *tdv1alpha1.Game, condn metav1.Condition) error {
+	g.calls = append(g.calls, "initialize "+obj.Name)
+	obj.Status.Conditions = []metav1.Condition{condn}
+	return nil
+}
+
+func (g *gameController) UpdateStatus(ctx context.Context, obj *tdv1alpha1.Game) error {
+	g.calls = append(g.calls, "update status "+obj.Name)
+	return nil
+}
+
+func (g *gameController) Operate(ctx context.Context, obj *tdv1alpha1.Game) (ctrl.Result, error) {
+	g.calls = append(g.calls, "operate "+obj.Name)
+	return ctrl.Result{}, nil
+}
+
+func (g *gameController) Cleanup(ctx context.Context, obj *tdv1alpha1.Game) (ctrl.Result, error) {
+	g.calls = append(g.calls, "cleanup "+obj.Name)
+	return ctrl.Result{}, nil
+}
+
+func TestCompositeReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameNamespacedName := types.NamespacedName{
+		Name:      "test-game",
+		Namespace: "test-ns",
+	}
+
+	gameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(gameObj).
+		Build()
+
+	gc := &gameController{}
+	logMiddleware := func(next ReconcileFunc[*tdv1alpha1.Game]) ReconcileFunc[*tdv1alpha1.Game] {
+		return func(ctx context.Context, obj *tdv1alpha1.Game) (ctrl.Result, error) {
+			gc.calls = append(gc.calls, "middleware "+obj.Name)
+			return next(ctx, obj)
+		}
+	}
+
+	cr := &CompositeReconciler[*tdv1alpha1.Game]{}
+	assert.Nil(t, cr.Init(nil, gc,
+		compositev1.WithScheme(scheme),
+		compositev1.WithClient(cli),
+		WithMiddleware(logMiddleware),
+	))
+
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: gameNamespacedName}
+
+	// First reconciliation initializes the object.
+	result, err := cr.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, result.Requeue)
+
+	// Second reconciliation operates.
+	_, err = cr.Reconcile(ctx, req)
+	assert.Nil(t, err)
+
+	wantCalls := []string{
+		"default test-game",
+		"validate test-game",
+		"initialize test-game",
+		"default test-game",
+		"validate test-game",
+		"middleware test-game",
+		"operate test-game",
+		"update status test-game",
+	}
+	assert.Equal(t, wantCalls, gc.calls)
+}
+
+func TestCompositeReconcilerInvalidType(t *testing.T) {
+	cr := &CompositeReconciler[client.Object]{}
+	assert.Error(t, cr.Init(nil, nil))
+}
@@ -0,0 +1,40 @@
+package v2
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Controller is the typed variant of the v1 Controller interface, for the
+// parent objects of type T. See the v1 Controller for the description of the
+// methods.
+type Controller[T client.Object] interface {
+	// Default applies default values to the parent object spec.
+	Default(context.Context, T)
+
+	// Validate validates the parent object spec.
+	Validate(context.Context, T) error
+
+	// Initialize sets the provided initialization condition on the object
+	// status.
+	Initialize(context.Context, T, metav1.Condition) error
+
+	// UpdateStatus sets the status of the parent object based on the state
+	// of the child objects.
+	UpdateStatus(context.Context, T) error
+
+	// Operate ensures that the child objects are in the desired state.
+	Operate(context.Context, T) (result ctrl.Result, err error)
+
+	// Cleanup runs the custom cleanup of the parent object.
+	Cleanup(context.Context, T) (result ctrl.Result, err error)
+}
+
+// Forgetter is the typed variant of the v1 Forgetter interface. A Controller
+// that implements it is told to forget the deleted parent objects.
+type Forgetter[T client.Object] interface {
+	Forget(context.Context, T)
+}
@@ -0,0 +1,6 @@
+// Package v2 contains the typed variant of the composite controller reconciler
+// of the v1 package. The Controller methods receive the parent object with its
+// concrete type, like *appv1.Game, instead of a client.Object. The
+// reconciliation is the one of the v1 CompositeReconciler, configured with the
+// same options.
+package v2
@@ -9,6 +9,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/ondat/operator-toolkit/object"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 const (
@@ -107,7 +108,10 @@ func (s *Reconciler) collectGarbage() {
 		instance.SetName(obj.Name)
 		instance.SetNamespace(obj.Namespace)
 		if err := controller.Delete(ctx, instance); err != nil {
+			metrics.GarbageCollectionDeletions.WithLabelValues(s.Name, metrics.ResultError).Inc()
 			log.Error(err, "failed to delete external object", "instance", instance)
+			continue
 		}
+		metrics.GarbageCollectionDeletions.WithLabelValues(s.Name, metrics.ResultSuccess).Inc()
 	}
 }
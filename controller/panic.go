@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
+)
+
+// ErrPanic is matched by the errors of recovered panics with errors.Is, also
+// when aggregated.
+var ErrPanic = errors.New("panic")
+
+// PanicError is the error of a recovered panic, with the stack trace of the
+// goroutine that panicked.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace at the time of the recovery.
+	Stack string
+}
+
+// NewPanicError returns a PanicError of the given recovered value. It must be
+// called in the deferred function that recovered the panic for the stack
+// trace to include the panicking function.
+func NewPanicError(value interface{}) *PanicError {
+	return &PanicError{Value: value, Stack: string(debug.Stack())}
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Is implements errors.Is for ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// IsPanic returns true if the error is, wraps or aggregates a PanicError.
+func IsPanic(err error) bool {
+	return errors.Is(err, ErrPanic)
+}
+
+// RecordPanic records the recovered panic in the span, with its stack trace,
+// and counts it in the panic metrics by component and name, like "operand"
+// and the operand name.
+func RecordPanic(span trace.Span, component, name string, perr *PanicError) {
+	span.RecordError(perr, trace.WithAttributes(semconv.ExceptionStacktraceKey.String(perr.Stack)))
+	span.SetStatus(codes.Error, perr.Error())
+	metrics.Panics.WithLabelValues(component, name).Inc()
+}
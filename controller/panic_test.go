@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func TestIsPanic(t *testing.T) {
+	var perr *PanicError
+	func() {
+		defer func() {
+			perr = NewPanicError(recover())
+		}()
+		panic("boom")
+	}()
+
+	assert.Equal(t, "panic: boom", perr.Error())
+	assert.Contains(t, perr.Stack, "TestIsPanic")
+
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "other error", err: errors.New("foo")},
+		{name: "panic", err: perr, want: true},
+		{name: "wrapped", err: fmt.Errorf("operand %q: %w", "foo", perr), want: true},
+		{name: "aggregated", err: kerrors.NewAggregate([]error{errors.New("foo"), perr}), want: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsPanic(tc.err))
+		})
+	}
+}
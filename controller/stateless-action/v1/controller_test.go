@@ -8,9 +8,12 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 
+	tkctrl "github.com/ondat/operator-toolkit/controller"
 	"github.com/ondat/operator-toolkit/controller/stateless-action/v1/action"
 	actionmocks "github.com/ondat/operator-toolkit/controller/stateless-action/v1/action/mocks"
 	"github.com/ondat/operator-toolkit/controller/stateless-action/v1/mocks"
@@ -108,6 +111,7 @@ func TestRunAction(t *testing.T) {
 		name         string
 		expectations func(m *actionmocks.MockManager)
 		wantErr      bool
+		wantPanic    bool
 	}{
 		{
 			name: "get name failure",
@@ -156,6 +160,19 @@ func TestRunAction(t *testing.T) {
 				m.EXPECT().Check(gomock.Any(), objA).Return(false, nil).After(check1)
 			},
 		},
+		{
+			name: "run panic",
+			expectations: func(m *actionmocks.MockManager) {
+				m.EXPECT().GetName(gomock.Any()).Return(testActionManagerName, nil)
+				m.EXPECT().Run(gomock.Any(), objA).Do(func(ctx context.Context, o interface{}) {
+					panic("boom")
+				})
+				// The deferred function still runs.
+				m.EXPECT().Defer(gomock.Any(), objA)
+			},
+			wantErr:   true,
+			wantPanic: true,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -166,8 +183,10 @@ func TestRunAction(t *testing.T) {
 			m := actionmocks.NewMockManager(mctrl)
 			tc.expectations(m)
 
+			recorder := record.NewFakeRecorder(1)
 			r := &Reconciler{
 				actionTimeout: 5 * time.Second,
+				recorder:      recorder,
 				inst:          telemetry.NewInstrumentation(instrumentationName),
 			}
 
@@ -177,6 +196,16 @@ func TestRunAction(t *testing.T) {
 			} else {
 				assert.Nil(t, actionErr)
 			}
+			assert.Equal(t, tc.wantPanic, tkctrl.IsPanic(actionErr))
+
+			// A Warning event is recorded on the reconciled object only for
+			// a panic.
+			r.recordActionPanic(&corev1.Pod{}, actionErr)
+			if tc.wantPanic {
+				assert.Contains(t, <-recorder.Events, ReasonActionPanic)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
 		})
 	}
 }
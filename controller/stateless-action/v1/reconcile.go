@@ -10,13 +10,16 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/ondat/operator-toolkit/constant"
 	tkctrl "github.com/ondat/operator-toolkit/controller"
 	"github.com/ondat/operator-toolkit/controller/stateless-action/v1/action"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
 	"github.com/ondat/operator-toolkit/telemetry"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 // Name of the instrumentation.
@@ -31,9 +34,14 @@ type Reconciler struct {
 
 	actionRetryPeriod time.Duration
 	actionTimeout     time.Duration
+	recorder          record.EventRecorder
 	inst              *telemetry.Instrumentation
 }
 
+// ReasonActionPanic is the reason of the Warning event recorded on the
+// reconciled object when one of its actions panics.
+const ReasonActionPanic = "ActionPanic"
+
 // ReconcilerOption is used to configure Reconciler.
 type ReconcilerOption func(*Reconciler)
 
@@ -57,6 +65,14 @@ func WithActionTimeout(duration time.Duration) ReconcilerOption {
 	}
 }
 
+// WithEventRecorder sets the event recorder of the Reconciler. By default, the
+// event recorder of the manager is used.
+func WithEventRecorder(recorder record.EventRecorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.recorder = recorder
+	}
+}
+
 // WithScheme sets the runtime Scheme of the Reconciler.
 func WithScheme(scheme *runtime.Scheme) ReconcilerOption {
 	return func(r *Reconciler) {
@@ -90,6 +106,11 @@ func (r *Reconciler) Init(mgr ctrl.Manager, ctrlr Controller, opts ...Reconciler
 		opt(r)
 	}
 
+	// If event recorder is not provided, use the manager's event recorder.
+	if r.recorder == nil && mgr != nil {
+		r.recorder = mgr.GetEventRecorderFor(r.name)
+	}
+
 	// If instrumentation is nil, create a new instrumentation with default
 	// providers.
 	if r.inst == nil {
@@ -103,6 +124,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 
 	start := time.Now()
 	defer tkctrl.LogReconcileFinish(log, "reconciliation finished", start, &result, &reterr)
+	defer metrics.ObserveReconcile("stateless-action", r.name, start, &result, &reterr)
 
 	span.SetAttributes(attribute.String("object-key", req.NamespacedName.String()))
 
@@ -173,9 +195,10 @@ func (r *Reconciler) RunActionManager(ctx context.Context, o interface{}) error
 
 	// Run the action in a goroutine.
 	for _, obj := range objects {
-		go func(o interface{}) {
-			if runErr := r.RunAction(actmgr, o); runErr != nil {
+		go func(obj interface{}) {
+			if runErr := r.RunAction(actmgr, obj); runErr != nil {
 				log.Error(runErr, "failed to run action")
+				r.recordActionPanic(o, runErr)
 			}
 		}(obj)
 	}
@@ -183,9 +206,28 @@ func (r *Reconciler) RunActionManager(ctx context.Context, o interface{}) error
 	return nil
 }
 
+// recordActionPanic records a Warning event on the reconciled object if the
+// given action error is a recovered panic.
+func (r *Reconciler) recordActionPanic(o interface{}, err error) {
+	if r.recorder == nil || !tkctrl.IsPanic(err) {
+		return
+	}
+	if obj, ok := o.(runtime.Object); ok {
+		r.recorder.Eventf(obj, eventv1.K8sEventTypeWarning, ReasonActionPanic, "Action panicked: %v", err)
+	}
+}
+
 // RunAction checks if an action needs to be run before running it. It also
-// runs a deferred function at the end.
+// runs a deferred function at the end. A panic in the action manager is
+// recovered and returned as a controller.PanicError.
 func (r *Reconciler) RunAction(actmgr action.Manager, o interface{}) (retErr error) {
+	var name string
+	defer func() {
+		if p := recover(); p != nil {
+			retErr = r.actionPanicked(name, tkctrl.NewPanicError(p))
+		}
+	}()
+
 	name, err := actmgr.GetName(o)
 	if err != nil {
 		retErr = errors.Wrapf(err, "failed to get action manager name")
@@ -236,6 +278,7 @@ func (r *Reconciler) RunAction(actmgr action.Manager, o interface{}) (retErr err
 			}
 			if checkResult {
 				span.AddEvent("Check result true, rerun action")
+				metrics.ActionRetries.WithLabelValues(r.name).Inc()
 				if runErr := actmgr.Run(ctx, o); runErr != nil {
 					log.Error(runErr, "action run retry failed")
 				}
@@ -250,3 +293,16 @@ func (r *Reconciler) RunAction(actmgr action.Manager, o interface{}) (retErr err
 		}
 	}
 }
+
+// actionPanicked records the recovered panic of an action and returns it as
+// the error of the action.
+func (r *Reconciler) actionPanicked(name string, perr *tkctrl.PanicError) error {
+	_, span, log := r.inst.Start(context.Background(), r.name+": action panic")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("actionName", name))
+	tkctrl.RecordPanic(span, metrics.ComponentAction, r.name, perr)
+	log.Error(perr, "action panicked", "action", name, "stack", perr.Stack)
+
+	return errors.Wrapf(perr, "action %q", name)
+}
@@ -0,0 +1,97 @@
+package action
+
+import (
+	"context"
+	"fmt"
+
+	actionv1 "github.com/ondat/operator-toolkit/controller/stateless-action/v1/action"
+)
+
+// Manager is the typed variant of the v1 action Manager, for the action
+// target objects of type O.
+type Manager[O any] interface {
+	// GetName returns the name of the Manager based on the target object.
+	GetName(O) (string, error)
+
+	// GetObjects returns all the objects on which action should be run.
+	GetObjects(context.Context) ([]O, error)
+
+	// Check checks if the action is needed anymore.
+	Check(context.Context, O) (bool, error)
+
+	// Run runs the action on the given object.
+	Run(context.Context, O) error
+
+	// Defer is executed at the end of run to execute once run ends.
+	Defer(context.Context, O) error
+}
+
+// Untyped returns the v1 action Manager of the given typed Manager.
+func Untyped[O any](m Manager[O]) actionv1.Manager {
+	return &manager[O]{m: m}
+}
+
+// manager adapts a typed Manager to the v1 action Manager interface.
+type manager[O any] struct {
+	m Manager[O]
+}
+
+var _ actionv1.Manager = &manager[any]{}
+
+// cast returns the object as O. A nil object is returned as the zero value
+// of O.
+func cast[O any](o interface{}) (O, error) {
+	var zero O
+	if o == nil {
+		return zero, nil
+	}
+	t, ok := o.(O)
+	if !ok {
+		return zero, fmt.Errorf("unexpected action object type %T", o)
+	}
+	return t, nil
+}
+
+func (m *manager[O]) GetName(o interface{}) (string, error) {
+	t, err := cast[O](o)
+	if err != nil {
+		return "", err
+	}
+	return m.m.GetName(t)
+}
+
+func (m *manager[O]) GetObjects(ctx context.Context) ([]interface{}, error) {
+	objects, err := m.m.GetObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(objects))
+	for _, o := range objects {
+		result = append(result, o)
+	}
+	return result, nil
+}
+
+func (m *manager[O]) Check(ctx context.Context, o interface{}) (bool, error) {
+	t, err := cast[O](o)
+	if err != nil {
+		return false, err
+	}
+	return m.m.Check(ctx, t)
+}
+
+func (m *manager[O]) Run(ctx context.Context, o interface{}) error {
+	t, err := cast[O](o)
+	if err != nil {
+		return err
+	}
+	return m.m.Run(ctx, t)
+}
+
+func (m *manager[O]) Defer(ctx context.Context, o interface{}) error {
+	t, err := cast[O](o)
+	if err != nil {
+		return err
+	}
+	return m.m.Defer(ctx, t)
+}
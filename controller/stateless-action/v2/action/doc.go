@@ -0,0 +1,3 @@
+// Package action defines the typed variant of the action Manager interface of
+// the v1 action package, for the action target objects of a concrete type.
+package action
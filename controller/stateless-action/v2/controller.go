@@ -0,0 +1,25 @@
+package v2
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/controller/stateless-action/v2/action"
+)
+
+// Controller is the typed variant of the v1 Controller interface, for the
+// reconciled objects of type T and the action target objects of type O.
+type Controller[T any, O any] interface {
+	// GetObject fetches an instance of an object being reconciled. A nil
+	// object means that the object doesn't exist.
+	GetObject(context.Context, client.ObjectKey) (T, error)
+
+	// RequireAction evaluates the target object to find out of the action must
+	// be executed on it.
+	RequireAction(context.Context, T) (bool, error)
+
+	// BuildActionManager builds an action manager that manages the actions to
+	// be executed.
+	BuildActionManager(T) (action.Manager[O], error)
+}
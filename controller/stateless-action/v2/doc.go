@@ -0,0 +1,6 @@
+// Package v2 contains the typed variant of the stateless-action controller
+// reconciler of the v1 package. The Controller methods receive the reconciled
+// object with its concrete type and build a typed action Manager, instead of
+// passing empty interfaces. The reconciliation and the execution of the
+// actions are the ones of the v1 Reconciler, configured with the same options.
+package v2
@@ -0,0 +1,87 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	actionsv1 "github.com/ondat/operator-toolkit/controller/stateless-action/v1"
+	actionv1 "github.com/ondat/operator-toolkit/controller/stateless-action/v1/action"
+	"github.com/ondat/operator-toolkit/controller/stateless-action/v2/action"
+)
+
+// Reconciler is the typed variant of the v1 Reconciler, for the reconciled
+// objects of type T and the action target objects of type O.
+type Reconciler[T any, O any] struct {
+	actionsv1.Reconciler
+}
+
+// Init initializes the Reconciler with the given typed Controller and the v1
+// Reconciler options.
+func (r *Reconciler[T, O]) Init(mgr ctrl.Manager, ctrlr Controller[T, O], opts ...actionsv1.ReconcilerOption) {
+	r.Reconciler.Init(mgr, &controller[T, O]{ctrlr: ctrlr}, opts...)
+}
+
+// controller adapts a typed Controller to the v1 Controller interface.
+type controller[T any, O any] struct {
+	ctrlr Controller[T, O]
+}
+
+var _ actionsv1.Controller = &controller[any, any]{}
+
+// isNil returns true if the value is nil or a nil pointer, map, slice or
+// interface.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface, reflect.Chan, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// cast returns the object as T. The objects are always of type T, returned
+// by GetObject.
+func cast[T any](o interface{}) (T, error) {
+	t, ok := o.(T)
+	if !ok {
+		return t, fmt.Errorf("unexpected object type %T", o)
+	}
+	return t, nil
+}
+
+func (c *controller[T, O]) GetObject(ctx context.Context, key client.ObjectKey) (interface{}, error) {
+	obj, err := c.ctrlr.GetObject(ctx, key)
+	// A nil typed object must be returned as nil for the reconciler to skip
+	// the missing objects.
+	if isNil(obj) {
+		return nil, err
+	}
+	return obj, err
+}
+
+func (c *controller[T, O]) RequireAction(ctx context.Context, o interface{}) (bool, error) {
+	t, err := cast[T](o)
+	if err != nil {
+		return false, err
+	}
+	return c.ctrlr.RequireAction(ctx, t)
+}
+
+func (c *controller[T, O]) BuildActionManager(o interface{}) (actionv1.Manager, error) {
+	t, err := cast[T](o)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.ctrlr.BuildActionManager(t)
+	if err != nil {
+		return nil, err
+	}
+	return action.Untyped(m), nil
+}
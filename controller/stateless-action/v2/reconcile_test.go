@@ -0,0 +1,119 @@
+package v2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	actionsv1 "github.com/ondat/operator-toolkit/controller/stateless-action/v1"
+	"github.com/ondat/operator-toolkit/controller/stateless-action/v2/action"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+// gameController is a typed Controller that records the calls.
+type gameController struct {
+	game  *tdv1alpha1.Game
+	mgr   *playerManager
+	calls []string
+}
+
+var _ Controller[*tdv1alpha1.Game, string] = &gameController{}
+
+func (g *gameController) GetObject(ctx context.Context, key client.ObjectKey) (*tdv1alpha1.Game, error) {
+	g.calls = append(g.calls, "get "+key.Name)
+	return g.game, nil
+}
+
+func (g *gameController) RequireAction(ctx context.Context, obj *tdv1alpha1.Game) (bool, error) {
+	g.calls = append(g.calls, "require action "+obj.Name)
+	return true, nil
+}
+
+func (g *gameController) BuildActionManager(obj *tdv1alpha1.Game) (action.Manager[string], error) {
+	g.calls = append(g.calls, "build action manager "+obj.Name)
+	return g.mgr, nil
+}
+
+// playerManager is a typed action Manager that records the calls.
+type playerManager struct {
+	calls []string
+}
+
+var _ action.Manager[string] = &playerManager{}
+
+func (p *playerManager) GetName(o string) (string, error) { return "player-" + o, nil }
+
+func (p *playerManager) GetObjects(ctx context.Context) ([]string, error) {
+	return []string{}, nil
+}
+
+func (p *playerManager) Check(ctx context.Context, o string) (bool, error) {
+	p.calls = append(p.calls, "check "+o)
+	return false, nil
+}
+
+func (p *playerManager) Run(ctx context.Context, o string) error {
+	p.calls = append(p.calls, "run "+o)
+	return nil
+}
+
+func (p *playerManager) Defer(ctx context.Context, o string) error {
+	p.calls = append(p.calls, "defer "+o)
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	testcases := []struct {
+		name      string
+		game      *tdv1alpha1.Game
+		wantCalls []string
+	}{
+		{
+			name:      "object not found",
+			wantCalls: []string{"get test-obj"},
+		},
+		{
+			name: "object found, action required",
+			game: &tdv1alpha1.Game{},
+			wantCalls: []string{
+				"get test-obj",
+				"require action ",
+				"build action manager ",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			gc := &gameController{game: tc.game, mgr: &playerManager{}}
+			r := &Reconciler[*tdv1alpha1.Game, string]{}
+			r.Init(nil, gc)
+
+			request := ctrl.Request{NamespacedName: types.NamespacedName{
+				Name:      "test-obj",
+				Namespace: "test-ns",
+			}}
+			_, err := r.Reconcile(context.Background(), request)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantCalls, gc.calls)
+		})
+	}
+}
+
+func TestRunAction(t *testing.T) {
+	pm := &playerManager{}
+	r := &Reconciler[*tdv1alpha1.Game, string]{}
+	r.Init(nil, &gameController{}, actionsv1.WithActionTimeout(5*time.Second))
+
+	assert.Nil(t, r.RunAction(action.Untyped[string](pm), "a"))
+	assert.Equal(t, []string{"run a", "check a", "defer a"}, pm.calls)
+
+	// Objects of another type are rejected.
+	assert.Error(t, r.RunAction(action.Untyped[string](pm), 1))
+}
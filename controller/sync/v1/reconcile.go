@@ -10,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	tkctrl "github.com/ondat/operator-toolkit/controller"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 func (s *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
@@ -18,6 +19,7 @@ func (s *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 
 	start := time.Now()
 	defer tkctrl.LogReconcileFinish(log, "reconciliation finished", start, &result, &reterr)
+	defer metrics.ObserveReconcile("sync", s.Name, start, &result, &reterr)
 
 	controller := s.Ctrlr
 
@@ -114,6 +114,8 @@ func (s *Reconciler) Init(mgr ctrl.Manager, ctrlr Controller, prototype client.O
 // RunSyncFuncs runs all the SyncFuncs in go routines.
 func (s *Reconciler) RunSyncFuncs() {
 	for _, sf := range s.SyncFuncs {
+		sf.reconciler = s.Name
+		sf.inst = s.Inst
 		go sf.Run()
 	}
 }
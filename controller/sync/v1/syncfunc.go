@@ -1,7 +1,12 @@
 package v1
 
 import (
+	"context"
 	"time"
+
+	tkctrl "github.com/ondat/operator-toolkit/controller"
+	"github.com/ondat/operator-toolkit/telemetry"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 const (
@@ -17,6 +22,11 @@ type SyncFunc struct {
 	f                func()
 	period           time.Duration
 	startupSyncDelay time.Duration
+	// reconciler is the name of the reconciler running the SyncFunc, used
+	// in the metrics.
+	reconciler string
+	// inst is the instrumentation of the reconciler running the SyncFunc.
+	inst *telemetry.Instrumentation
 }
 
 // NewSyncFunc returns a new SyncFunc, given a function and a sync period.
@@ -54,7 +64,27 @@ func (sf SyncFunc) Run() {
 	}
 }
 
-// Call calls the SyncFunc function.
+// Call calls the SyncFunc function. A panic in the function is recovered,
+// recorded and counted, and the function is called again at the next period.
 func (sf SyncFunc) Call() {
+	metrics.ResyncTotal.WithLabelValues(sf.reconciler).Inc()
+	defer func() {
+		if r := recover(); r != nil {
+			sf.recordPanic(tkctrl.NewPanicError(r))
+		}
+	}()
 	sf.f()
 }
+
+// recordPanic records a panic of the SyncFunc function.
+func (sf SyncFunc) recordPanic(perr *tkctrl.PanicError) {
+	inst := sf.inst
+	if inst == nil {
+		inst = telemetry.NewInstrumentation(instrumentationName)
+	}
+	_, span, log := inst.Start(context.Background(), "SyncFunc panic")
+	defer span.End()
+
+	tkctrl.RecordPanic(span, metrics.ComponentSyncFunc, sf.reconciler, perr)
+	log.Error(perr, "sync function panicked", "stack", perr.Stack)
+}
@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
+)
+
+func TestSyncFuncCallPanic(t *testing.T) {
+	calls := 0
+	sf := NewSyncFunc(func() {
+		calls++
+		panic("boom")
+	}, time.Minute, time.Second)
+	sf.reconciler = "panic-test"
+
+	// The panic is recovered and the function can be called again.
+	assert.NotPanics(t, sf.Call)
+	assert.NotPanics(t, sf.Call)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.Panics.WithLabelValues(metrics.ComponentSyncFunc, "panic-test")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ResyncTotal.WithLabelValues("panic-test")))
+}
@@ -0,0 +1,20 @@
+package v2
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Controller is the typed variant of the v1 Controller interface, for the
+// objects of type T.
+type Controller[T client.Object] interface {
+	// Ensure receives a k8s object and calls an external system's API to
+	// ensure an associated object exists in the external system.
+	Ensure(context.Context, T) error
+
+	// Delete receives a k8s object that's deleted and calls an external
+	// system's API to delete the associated object in the external system.
+	// Only the name and namespace of the object are set.
+	Delete(context.Context, T) error
+}
@@ -0,0 +1,6 @@
+// Package v2 contains the typed variant of the sync controller reconciler of
+// the v1 package. The Controller methods receive the object with its concrete
+// type, like *appv1.Game, instead of a client.Object. The reconciliation and
+// the sync functions are the ones of the v1 Reconciler, configured with the
+// same options.
+package v2
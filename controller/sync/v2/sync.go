@@ -0,0 +1,66 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	syncv1 "github.com/ondat/operator-toolkit/controller/sync/v1"
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// Reconciler is the typed variant of the v1 Reconciler, for the objects of
+// type T and their lists of type L, which must be pointers to struct types.
+type Reconciler[T client.Object, L client.ObjectList] struct {
+	syncv1.Reconciler
+}
+
+// Init initializes the Reconciler with the given typed Controller and the v1
+// Reconciler options. The prototypes of the reconciled objects and lists are
+// new objects of type T and L.
+func (s *Reconciler[T, L]) Init(mgr ctrl.Manager, ctrlr Controller[T], opts ...syncv1.ReconcilerOption) error {
+	prototype, err := object.New[T]()
+	if err != nil {
+		return fmt.Errorf("invalid object type: %w", err)
+	}
+	prototypeList, err := object.New[L]()
+	if err != nil {
+		return fmt.Errorf("invalid object list type: %w", err)
+	}
+	return s.Reconciler.Init(mgr, &controller[T]{ctrlr: ctrlr}, prototype, prototypeList, opts...)
+}
+
+// controller adapts a typed Controller to the v1 Controller interface.
+type controller[T client.Object] struct {
+	ctrlr Controller[T]
+}
+
+var _ syncv1.Controller = &controller[client.Object]{}
+
+// cast returns the object as T. The reconciled objects are always of type T,
+// created from the prototype.
+func cast[T client.Object](obj client.Object) (T, error) {
+	t, ok := obj.(T)
+	if !ok {
+		return t, fmt.Errorf("unexpected object type %T", obj)
+	}
+	return t, nil
+}
+
+func (c *controller[T]) Ensure(ctx context.Context, obj client.Object) error {
+	t, err := cast[T](obj)
+	if err != nil {
+		return err
+	}
+	return c.ctrlr.Ensure(ctx, t)
+}
+
+func (c *controller[T]) Delete(ctx context.Context, obj client.Object) error {
+	t, err := cast[T](obj)
+	if err != nil {
+		return err
+	}
+	return c.ctrlr.Delete(ctx, t)
+}
@@ -0,0 +1,70 @@
+package v2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	syncv1 "github.com/ondat/operator-toolkit/controller/sync/v1"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+// gameController is a typed Controller that records the calls.
+type gameController struct {
+	calls []string
+}
+
+var _ Controller[*tdv1alpha1.Game] = &gameController{}
+
+func (g *gameController) Ensure(ctx context.Context, obj *tdv1alpha1.Game) error {
+	g.calls = append(g.calls, "ensure "+obj.Namespace+"/"+obj.Name)
+	return nil
+}
+
+func (g *gameController) Delete(ctx context.Context, obj *tdv1alpha1.Game) error {
+	g.calls = append(g.calls, "delete "+obj.Namespace+"/"+obj.Name)
+	return nil
+}
+
+func TestReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameObj := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-game",
+			Namespace: "test-ns",
+		},
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(gameObj).
+		Build()
+
+	gc := &gameController{}
+	sr := &Reconciler[*tdv1alpha1.Game, *tdv1alpha1.GameList]{}
+	assert.Nil(t, sr.Init(nil, gc,
+		syncv1.WithScheme(scheme),
+		syncv1.WithClient(cli),
+	))
+
+	ctx := context.Background()
+	for _, name := range []string{"test-game", "deleted-game"} {
+		_, err := sr.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "test-ns", Name: name}})
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"ensure test-ns/test-game", "delete test-ns/deleted-game"}, gc.calls)
+}
+
+func TestReconcilerInvalidType(t *testing.T) {
+	sr := &Reconciler[*tdv1alpha1.Game, client.ObjectList]{}
+	assert.Error(t, sr.Init(nil, nil))
+}
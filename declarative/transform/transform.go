@@ -10,6 +10,8 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
+
+	"github.com/ondat/operator-toolkit/object"
 )
 
 // TransformFunc is the type of a transform function. A transformation must
@@ -84,6 +86,21 @@ func AddLabelsFunc(labels map[string]string) TransformFunc {
 	}
 }
 
+// SetOwnershipLabels returns a TransformFunc that sets the ownership labels
+// and annotations of an object to refer to the given owner.
+func SetOwnershipLabels(owner object.Owner) TransformFunc {
+	return func(obj *yaml.RNode) error {
+		labels, err := owner.Labels()
+		if err != nil {
+			return err
+		}
+		if err := AddLabelsFunc(labels)(obj); err != nil {
+			return err
+		}
+		return AddAnnotationsFunc(owner.Annotations())(obj)
+	}
+}
+
 // AddAnnotationsFunc returns a TransformFunc that adds the given annotations
 // to an object.
 func AddAnnotationsFunc(annotations map[string]string) TransformFunc {
@@ -6,9 +6,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 
 	"github.com/ondat/operator-toolkit/declarative/loader"
+	"github.com/ondat/operator-toolkit/object"
 )
 
 func TestSetOwnerReference(t *testing.T) {
@@ -67,6 +69,46 @@ metadata:
 	assert.Equal(t, wantManifest, string(b))
 }
 
+func TestSetOwnershipLabels(t *testing.T) {
+	obj, err := yaml.Parse(`apiVersion: example.com/v1
+kind: DB
+metadata:
+  name: test-db
+  labels:
+    app: db
+`)
+	assert.Nil(t, err)
+
+	owner := object.Owner{
+		GroupVersionKind: schema.GroupVersionKind{Group: "someapi", Version: "v1", Kind: "Somekind"},
+		Namespace:        "default",
+		Name:             "somename",
+		UID:              "17d16671-513f-4026-9302-904fe90601cf",
+	}
+	assert.Nil(t, SetOwnershipLabels(owner)(obj))
+
+	wantLabels := map[string]string{
+		"app":                "db",
+		object.LabelOwnerUID: "17d16671-513f-4026-9302-904fe90601cf",
+	}
+	assert.Equal(t, wantLabels, obj.GetLabels())
+	wantAnnotations := map[string]string{
+		object.AnnotationOwnerGVK:       "Somekind.v1.someapi",
+		object.AnnotationOwnerNamespace: "default",
+		object.AnnotationOwnerName:      "somename",
+	}
+	assert.Equal(t, wantAnnotations, obj.GetAnnotations())
+
+	// Names longer than a label value are kept in the annotations.
+	owner.Name = strings.Repeat("a", 64)
+	assert.Nil(t, SetOwnershipLabels(owner)(obj))
+	assert.Equal(t, owner.Name, obj.GetAnnotations()[object.AnnotationOwnerName])
+
+	// An owner without UID fails the transform.
+	owner.UID = ""
+	assert.Error(t, SetOwnershipLabels(owner)(obj))
+}
+
 func TestReplicaTransform(t *testing.T) {
 	// Create an in-memory filesystem and load the packages in it.
 	fs, err := loader.NewLoadedManifestFileSystem("../testdata/channels", "")
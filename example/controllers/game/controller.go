@@ -45,13 +45,21 @@ func (gc *GameController) Initialize(ctx context.Context, obj client.Object, con
 }
 
 func (gc *GameController) Operate(ctx context.Context, obj client.Object) (result ctrl.Result, err error) {
-	return gc.Operator.Ensure(ctx, obj, object.OwnerReferenceFromObject(obj))
+	result, _, err = gc.Operator.Ensure(ctx, obj, object.OwnerReferenceFromObject(obj))
+	return
 }
 
 func (gc *GameController) Cleanup(context.Context, client.Object) (result ctrl.Result, err error) {
 	return ctrl.Result{}, nil
 }
 
+// Forget releases the state the operator keeps about a deleted Game.
+func (gc *GameController) Forget(ctx context.Context, obj client.Object) {
+	if f, ok := gc.Operator.(compositev1.Forgetter); ok {
+		f.Forget(ctx, obj)
+	}
+}
+
 func (gc *GameController) UpdateStatus(context.Context, client.Object) error {
 	return nil
 }
@@ -18,6 +18,7 @@ func NewOperator(mgr ctrl.Manager, fs filesys.FileSystem, execStrategy executor.
 
 	// Create and return CompositeOperator.
 	return operatorv1.NewCompositeOperator(
+		operatorv1.WithName("game-controller"),
 		operatorv1.WithEventRecorder(mgr.GetEventRecorderFor("game-controller")),
 		operatorv1.WithExecutionStrategy(execStrategy),
 		operatorv1.WithOperands(configmapOp),
@@ -8,6 +8,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -21,6 +22,44 @@ func OwnerReferenceFromObject(obj client.Object) metav1.OwnerReference {
 	}
 }
 
+// NewObject returns a new object of the given kind, typed if the kind is
+// registered in the scheme, or else unstructured.
+func NewObject(scheme *runtime.Scheme, gvk schema.GroupVersionKind) client.Object {
+	if obj, err := scheme.New(gvk); err == nil {
+		if cObj, ok := obj.(client.Object); ok {
+			return cObj
+		}
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return u
+}
+
+// NewObjectList returns a new list of the objects of the given kind, typed if
+// the list kind is registered in the scheme, or else unstructured.
+func NewObjectList(scheme *runtime.Scheme, gvk schema.GroupVersionKind) client.ObjectList {
+	listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+	if obj, err := scheme.New(listGVK); err == nil {
+		if list, ok := obj.(client.ObjectList); ok {
+			return list
+		}
+	}
+	u := &unstructured.UnstructuredList{}
+	u.SetGroupVersionKind(listGVK)
+	return u
+}
+
+// New returns a new empty object of the type T, which must be a pointer to a
+// struct type, like *corev1.Pod or *corev1.PodList.
+func New[T runtime.Object]() (T, error) {
+	var zero T
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return zero, fmt.Errorf("type %s is not a pointer to a struct", t)
+	}
+	return reflect.New(t.Elem()).Interface().(T), nil
+}
+
 // GetUnstructuredObject converts the given Object into Unstructured type.
 func GetUnstructuredObject(scheme *runtime.Scheme, obj runtime.Object) (*unstructured.Unstructured, error) {
 	u := &unstructured.Unstructured{}
@@ -68,6 +107,43 @@ func GetObjectStatus(obj map[string]interface{}) (map[string]interface{}, error)
 	return objStatus, nil
 }
 
+// GetObservedGeneration returns the observed generation of a given object,
+// stored in status.observedGeneration. The second returned value is false if
+// the observed generation is not set.
+func GetObservedGeneration(scheme *runtime.Scheme, obj runtime.Object) (int64, bool, error) {
+	u, err := GetUnstructuredObject(scheme, obj)
+	if err != nil {
+		return 0, false, err
+	}
+	gen, found, err := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get observed generation: %v", err)
+	}
+	return gen, found, nil
+}
+
+// SetObservedGeneration sets the given generation as the observed generation
+// of a given object in status.observedGeneration. For typed objects, the
+// status type must have an observedGeneration field for the value to be
+// retained.
+func SetObservedGeneration(scheme *runtime.Scheme, obj runtime.Object, generation int64) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return unstructured.SetNestedField(u.Object, generation, "status", "observedGeneration")
+	}
+
+	u, err := GetUnstructuredObject(scheme, obj)
+	if err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedField(u.Object, generation, "status", "observedGeneration"); err != nil {
+		return fmt.Errorf("failed to set observed generation: %v", err)
+	}
+	if err := scheme.Convert(u, obj, nil); err != nil {
+		return fmt.Errorf("failed to convert Unstructured to Object: %v", err)
+	}
+	return nil
+}
+
 // StatusChanged gets the status of the given objects and compares them. It
 // returns true if there's a change in the object status.
 func StatusChanged(scheme *runtime.Scheme, oldo runtime.Object, newo runtime.Object) (bool, error) {
@@ -7,6 +7,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
 )
@@ -460,3 +462,82 @@ func TestNestedFieldNoCopy(t *testing.T) {
 		})
 	}
 }
+
+func TestObservedGeneration(t *testing.T) {
+	// Create a scheme with testdata scheme info.
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	cases := []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{
+			name: "typed object",
+			obj: &tdv1alpha1.Game{
+				ObjectMeta: metav1.ObjectMeta{Name: "zelda", Namespace: "switch"},
+			},
+		},
+		{
+			name: "unstructured object",
+			obj: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "app.example.com/v1alpha1",
+					"kind":       "Game",
+					"metadata": map[string]interface{}{
+						"name":      "zelda",
+						"namespace": "switch",
+					},
+				},
+			},
+		},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, found, err := GetObservedGeneration(scheme, tc.obj)
+			assert.NoError(t, err)
+			assert.False(t, found, "observed generation found before set")
+
+			assert.NoError(t, SetObservedGeneration(scheme, tc.obj, 5))
+
+			gen, found, err := GetObservedGeneration(scheme, tc.obj)
+			assert.NoError(t, err)
+			assert.True(t, found, "observed generation not found after set")
+			assert.Equal(t, int64(5), gen)
+		})
+	}
+}
+
+func TestNewObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	gameGVK := tdv1alpha1.GroupVersion.WithKind("Game")
+	assert.IsType(t, &tdv1alpha1.Game{}, NewObject(scheme, gameGVK))
+	assert.IsType(t, &tdv1alpha1.GameList{}, NewObjectList(scheme, gameGVK))
+
+	// Kinds not registered in the scheme are unstructured.
+	widgetGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	obj := NewObject(scheme, widgetGVK)
+	if assert.IsType(t, &unstructured.Unstructured{}, obj) {
+		assert.Equal(t, widgetGVK, obj.GetObjectKind().GroupVersionKind())
+	}
+	list := NewObjectList(scheme, widgetGVK)
+	if assert.IsType(t, &unstructured.UnstructuredList{}, list) {
+		assert.Equal(t, widgetGVK.GroupVersion().WithKind("WidgetList"), list.GetObjectKind().GroupVersionKind())
+	}
+}
+
+func TestNew(t *testing.T) {
+	game, err := New[*tdv1alpha1.Game]()
+	assert.Nil(t, err)
+	assert.Equal(t, &tdv1alpha1.Game{}, game)
+
+	list, err := New[*tdv1alpha1.GameList]()
+	assert.Nil(t, err)
+	assert.Equal(t, &tdv1alpha1.GameList{}, list)
+
+	_, err = New[client.Object]()
+	assert.Error(t, err)
+}
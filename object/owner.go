@@ -0,0 +1,58 @@
+package object
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/constant"
+)
+
+const (
+	// AnnotationOwner is the annotation of an object with the owner of the
+	// object, as "<namespace>/<name>", or "<name>" for a cluster-scoped
+	// owner. It's used for the objects that can't have an owner reference to
+	// their owner, like cluster-scoped objects and objects in another
+	// namespace.
+	AnnotationOwner = constant.AnnotationPrefix + "owner"
+	// AnnotationOwnerKind is the annotation of an object with the kind of its
+	// owner, as "<kind>.<group>".
+	AnnotationOwnerKind = constant.AnnotationPrefix + "owner-kind"
+)
+
+// SetOwnerAnnotations sets the owner annotations of the object to refer to
+// the owner of the given kind and key.
+func SetOwnerAnnotations(obj client.Object, ownerKind schema.GroupKind, owner types.NamespacedName) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationOwner] = owner.String()
+	if owner.Namespace == "" {
+		annotations[AnnotationOwner] = owner.Name
+	}
+	annotations[AnnotationOwnerKind] = ownerKind.String()
+	obj.SetAnnotations(annotations)
+}
+
+// OwnerFromAnnotations returns the kind and key of the owner of the object
+// from its owner annotations. It returns false if the object has no valid
+// owner annotations.
+func OwnerFromAnnotations(obj client.Object) (schema.GroupKind, types.NamespacedName, bool) {
+	annotations := obj.GetAnnotations()
+	owner, kind := annotations[AnnotationOwner], annotations[AnnotationOwnerKind]
+	if owner == "" || kind == "" {
+		return schema.GroupKind{}, types.NamespacedName{}, false
+	}
+
+	key := types.NamespacedName{Name: owner}
+	if i := strings.Index(owner, "/"); i >= 0 {
+		key = types.NamespacedName{Namespace: owner[:i], Name: owner[i+1:]}
+	}
+	if key.Name == "" {
+		return schema.GroupKind{}, types.NamespacedName{}, false
+	}
+	return schema.ParseGroupKind(kind), key, true
+}
@@ -0,0 +1,102 @@
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOwnerAnnotations(t *testing.T) {
+	gameKind := schema.GroupKind{Group: "app.example.com", Kind: "Game"}
+
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		ownerKind       schema.GroupKind
+		owner           types.NamespacedName
+		wantAnnotations map[string]string
+	}{
+		{
+			name:      "namespaced owner",
+			ownerKind: gameKind,
+			owner:     types.NamespacedName{Namespace: "default", Name: "my-game"},
+			wantAnnotations: map[string]string{
+				AnnotationOwner:     "default/my-game",
+				AnnotationOwnerKind: "Game.app.example.com",
+			},
+		},
+		{
+			name:      "cluster-scoped owner of core kind",
+			ownerKind: schema.GroupKind{Kind: "Namespace"},
+			owner:     types.NamespacedName{Name: "foo"},
+			wantAnnotations: map[string]string{
+				AnnotationOwner:     "foo",
+				AnnotationOwnerKind: "Namespace",
+			},
+		},
+		{
+			name:        "keep other annotations",
+			annotations: map[string]string{"foo": "bar"},
+			ownerKind:   gameKind,
+			owner:       types.NamespacedName{Namespace: "default", Name: "my-game"},
+			wantAnnotations: map[string]string{
+				"foo":               "bar",
+				AnnotationOwner:     "default/my-game",
+				AnnotationOwnerKind: "Game.app.example.com",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			SetOwnerAnnotations(obj, tc.ownerKind, tc.owner)
+			assert.Equal(t, tc.wantAnnotations, obj.GetAnnotations())
+
+			kind, owner, ok := OwnerFromAnnotations(obj)
+			assert.True(t, ok)
+			assert.Equal(t, tc.ownerKind, kind)
+			assert.Equal(t, tc.owner, owner)
+		})
+	}
+}
+
+func TestOwnerFromAnnotationsInvalid(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{
+			name: "no annotations",
+		},
+		{
+			name:        "no owner kind",
+			annotations: map[string]string{AnnotationOwner: "default/my-game"},
+		},
+		{
+			name:        "no owner",
+			annotations: map[string]string{AnnotationOwnerKind: "Game.app.example.com"},
+		},
+		{
+			name: "no owner name",
+			annotations: map[string]string{
+				AnnotationOwner:     "default/",
+				AnnotationOwnerKind: "Game.app.example.com",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			_, _, ok := OwnerFromAnnotations(obj)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/constant"
+)
+
+// Ownership labels and annotations of an object, referring to the owner of
+// the object. Unlike an owner reference, they can refer to an owner in another
+// namespace or to a namespaced owner of a cluster-scoped object. The UID of
+// the owner is a label, so that the owned objects can be selected and indexed
+// by owner. The other fields of the owner are annotations, since they can
+// exceed the maximum length of a label value.
+const (
+	// LabelOwnerUID is the UID of the owner.
+	LabelOwnerUID = constant.AnnotationPrefix + "owner-uid"
+	// AnnotationOwnerGVK is the kind of the owner, as "<kind>.<version>" for
+	// the core group, or else "<kind>.<version>.<group>".
+	AnnotationOwnerGVK = constant.AnnotationPrefix + "owner-gvk"
+	// AnnotationOwnerNamespace is the namespace of the owner, empty for a
+	// cluster-scoped owner.
+	AnnotationOwnerNamespace = constant.AnnotationPrefix + "owner-namespace"
+	// AnnotationOwnerName is the name of the owner.
+	AnnotationOwnerName = constant.AnnotationPrefix + "owner-name"
+)
+
+// Owner is the owner of an object in its ownership labels.
+type Owner struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	UID              types.UID
+}
+
+// OwnerFromObject returns the Owner of the given owner object. The GVK of the
+// object must be set.
+func OwnerFromObject(obj client.Object) Owner {
+	return Owner{
+		GroupVersionKind: obj.GetObjectKind().GroupVersionKind(),
+		Namespace:        obj.GetNamespace(),
+		Name:             obj.GetName(),
+		UID:              obj.GetUID(),
+	}
+}
+
+// OwnerFromReference returns the Owner of the given owner reference. The
+// namespace is the namespace of the owner, usually the namespace of the parent
+// object the owner reference was created from.
+func OwnerFromReference(ref metav1.OwnerReference, namespace string) Owner {
+	return Owner{
+		GroupVersionKind: schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind),
+		Namespace:        namespace,
+		Name:             ref.Name,
+		UID:              ref.UID,
+	}
+}
+
+// Key returns the namespaced name of the owner.
+func (o Owner) Key() types.NamespacedName {
+	return types.NamespacedName{Namespace: o.Namespace, Name: o.Name}
+}
+
+// Labels returns the ownership labels of the objects owned by the owner. It
+// fails if the owner has no valid UID.
+func (o Owner) Labels() (map[string]string, error) {
+	if o.UID == "" {
+		return nil, fmt.Errorf("invalid ownership label %s: owner UID is empty", LabelOwnerUID)
+	}
+	if errs := validation.IsValidLabelValue(string(o.UID)); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid ownership label %s=%q: %s", LabelOwnerUID, o.UID, strings.Join(errs, "; "))
+	}
+	return map[string]string{LabelOwnerUID: string(o.UID)}, nil
+}
+
+// Annotations returns the ownership annotations of the objects owned by the
+// owner.
+func (o Owner) Annotations() map[string]string {
+	gvk := o.GroupVersionKind.Kind + "." + o.GroupVersionKind.Version
+	if o.GroupVersionKind.Group != "" {
+		gvk += "." + o.GroupVersionKind.Group
+	}
+
+	return map[string]string{
+		AnnotationOwnerGVK:       gvk,
+		AnnotationOwnerNamespace: o.Namespace,
+		AnnotationOwnerName:      o.Name,
+	}
+}
+
+// SetOwnershipLabels sets the ownership labels and annotations of the object
+// to refer to the given owner.
+func SetOwnershipLabels(obj client.Object, owner Owner) error {
+	ownerLabels, err := owner.Labels()
+	if err != nil {
+		return err
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range ownerLabels {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for k, v := range owner.Annotations() {
+		annotations[k] = v
+	}
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// OwnerFromLabels returns the owner of the object from its ownership labels
+// and annotations. It returns false if the object has no valid ownership
+// labels and annotations.
+func OwnerFromLabels(obj client.Object) (Owner, bool) {
+	annotations := obj.GetAnnotations()
+	gvk, name, uid := annotations[AnnotationOwnerGVK], annotations[AnnotationOwnerName], obj.GetLabels()[LabelOwnerUID]
+	if gvk == "" || name == "" || uid == "" {
+		return Owner{}, false
+	}
+
+	parts := strings.SplitN(gvk, ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Owner{}, false
+	}
+	owner := Owner{
+		GroupVersionKind: schema.GroupVersionKind{Kind: parts[0], Version: parts[1]},
+		Namespace:        annotations[AnnotationOwnerNamespace],
+		Name:             name,
+		UID:              types.UID(uid),
+	}
+	if len(parts) == 3 {
+		owner.GroupVersionKind.Group = parts[2]
+	}
+	return owner, true
+}
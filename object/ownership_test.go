@@ -0,0 +1,137 @@
+package object
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestOwnershipLabels(t *testing.T) {
+	cases := []struct {
+		name            string
+		owner           Owner
+		wantAnnotations map[string]string
+		wantErr         bool
+	}{
+		{
+			name: "namespaced owner",
+			owner: Owner{
+				GroupVersionKind: schema.GroupVersionKind{Group: "app.example.com", Version: "v1alpha1", Kind: "Game"},
+				Namespace:        "default",
+				Name:             "my-game",
+				UID:              "game-uid",
+			},
+			wantAnnotations: map[string]string{
+				AnnotationOwnerGVK:       "Game.v1alpha1.app.example.com",
+				AnnotationOwnerNamespace: "default",
+				AnnotationOwnerName:      "my-game",
+			},
+		},
+		{
+			name: "cluster-scoped owner of core kind",
+			owner: Owner{
+				GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+				Name:             "foo",
+				UID:              "ns-uid",
+			},
+			wantAnnotations: map[string]string{
+				AnnotationOwnerGVK:       "Namespace.v1",
+				AnnotationOwnerNamespace: "",
+				AnnotationOwnerName:      "foo",
+			},
+		},
+		{
+			name: "name longer than a label value",
+			owner: Owner{
+				GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace:        "default",
+				Name:             strings.Repeat("a", 253),
+				UID:              "pod-uid",
+			},
+			wantAnnotations: map[string]string{
+				AnnotationOwnerGVK:       "Pod.v1",
+				AnnotationOwnerNamespace: "default",
+				AnnotationOwnerName:      strings.Repeat("a", 253),
+			},
+		},
+		{
+			name: "no uid",
+			owner: Owner{
+				GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Namespace:        "default",
+				Name:             "foo",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}}}
+			err := SetOwnershipLabels(obj, tc.owner)
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Equal(t, map[string]string{"foo": "bar"}, obj.GetLabels())
+				assert.Empty(t, obj.GetAnnotations())
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"foo": "bar", LabelOwnerUID: string(tc.owner.UID)}, obj.GetLabels())
+			assert.Equal(t, tc.wantAnnotations, obj.GetAnnotations())
+
+			owner, ok := OwnerFromLabels(obj)
+			assert.True(t, ok)
+			assert.Equal(t, tc.owner, owner)
+		})
+	}
+}
+
+func TestOwnerFromLabelsInvalid(t *testing.T) {
+	cases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+	}{
+		{
+			name: "no labels",
+		},
+		{
+			name:        "no uid",
+			annotations: map[string]string{AnnotationOwnerGVK: "Pod.v1", AnnotationOwnerName: "foo"},
+		},
+		{
+			name:   "no annotations",
+			labels: map[string]string{LabelOwnerUID: "uid"},
+		},
+		{
+			name:        "no version",
+			labels:      map[string]string{LabelOwnerUID: "uid"},
+			annotations: map[string]string{AnnotationOwnerGVK: "Pod", AnnotationOwnerName: "foo"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Labels: tc.labels, Annotations: tc.annotations}}
+			_, ok := OwnerFromLabels(obj)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestOwnerFromReference(t *testing.T) {
+	ref := metav1.OwnerReference{APIVersion: "app.example.com/v1alpha1", Kind: "Game", Name: "my-game", UID: "game-uid"}
+	want := Owner{
+		GroupVersionKind: schema.GroupVersionKind{Group: "app.example.com", Version: "v1alpha1", Kind: "Game"},
+		Namespace:        "default",
+		Name:             "my-game",
+		UID:              "game-uid",
+	}
+	assert.Equal(t, want, OwnerFromReference(ref, "default"))
+}
@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+// operandBackoff tracks the exponential backoff of the not ready operands of
+// the parent objects. The backoff of an operand is keyed by the UID of the
+// parent object and the name of the operand.
+type operandBackoff struct {
+	limiter workqueue.RateLimiter
+
+	mu sync.Mutex
+	// operands are the names of the operands with a backoff, by UID of the
+	// parent object, to forget them once the object is deleted.
+	operands map[types.UID]map[string]bool
+}
+
+// newOperandBackoff returns an operandBackoff with the given base and maximum
+// wait periods.
+func newOperandBackoff(base, max time.Duration) *operandBackoff {
+	return &operandBackoff{
+		limiter:  workqueue.NewItemExponentialFailureRateLimiter(base, max),
+		operands: map[types.UID]map[string]bool{},
+	}
+}
+
+// backoffKey returns the backoff key of an operand of the given object.
+func backoffKey(obj client.Object, operandName string) string {
+	return string(obj.GetUID()) + "/" + operandName
+}
+
+// update updates the backoff of the operands in the given report. The backoff
+// of the not ready operands is increased and the backoff of the succeeded
+// operands is reset. It returns the minimum wait period across the not ready
+// operands and true if any not ready operand was found.
+func (b *operandBackoff) update(obj client.Object, report *executor.ExecutionReport) (time.Duration, bool) {
+	var wait time.Duration
+	found := false
+
+	if report == nil {
+		return wait, found
+	}
+
+	for _, r := range report.Operands {
+		switch r.State {
+		case executor.OperandNotReady:
+			d := b.when(obj, r.Name)
+			if !found || d < wait {
+				wait = d
+			}
+			found = true
+		case executor.OperandSucceeded:
+			b.forget(obj, r.Name)
+		}
+	}
+
+	return wait, found
+}
+
+// when increases the backoff of the named operand of the object and returns
+// its wait period.
+func (b *operandBackoff) when(obj client.Object, name string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names, ok := b.operands[obj.GetUID()]
+	if !ok {
+		names = map[string]bool{}
+		b.operands[obj.GetUID()] = names
+	}
+	names[name] = true
+	return b.limiter.When(backoffKey(obj, name))
+}
+
+// forget resets the backoff of the named operands of the object.
+func (b *operandBackoff) forget(obj client.Object, names ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range names {
+		b.limiter.Forget(backoffKey(obj, name))
+		delete(b.operands[obj.GetUID()], name)
+	}
+	if len(b.operands[obj.GetUID()]) == 0 {
+		delete(b.operands, obj.GetUID())
+	}
+}
+
+// forgetObject resets the backoff of all the operands of the object.
+func (b *operandBackoff) forgetObject(obj client.Object) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name := range b.operands[obj.GetUID()] {
+		b.limiter.Forget(backoffKey(obj, name))
+	}
+	delete(b.operands, obj.GetUID())
+}
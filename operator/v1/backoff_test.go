@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+func TestOperandBackoff(t *testing.T) {
+	podA := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "uid-a"}}
+	podB := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "uid-b"}}
+
+	report := func(states map[string]executor.OperandState) *executor.ExecutionReport {
+		r := &executor.ExecutionReport{}
+		for name, state := range states {
+			r.Operands = append(r.Operands, executor.OperandReport{Name: name, State: state})
+		}
+		return r
+	}
+
+	b := newOperandBackoff(time.Second, 8*time.Second)
+
+	// No not ready operand.
+	_, found := b.update(podA, report(map[string]executor.OperandState{
+		"opA": executor.OperandSucceeded,
+	}))
+	assert.False(t, found)
+
+	// The wait period of a not ready operand increases exponentially up to
+	// the maximum.
+	for _, want := range []time.Duration{1, 2, 4, 8, 8} {
+		wait, found := b.update(podA, report(map[string]executor.OperandState{
+			"opA": executor.OperandSucceeded,
+			"opB": executor.OperandNotReady,
+		}))
+		assert.True(t, found)
+		assert.Equal(t, want*time.Second, wait)
+	}
+
+	// The minimum wait period across the not ready operands is returned.
+	wait, _ := b.update(podA, report(map[string]executor.OperandState{
+		"opB": executor.OperandNotReady,
+		"opC": executor.OperandNotReady,
+	}))
+	assert.Equal(t, time.Second, wait)
+
+	// The backoff is per parent object.
+	wait, _ = b.update(podB, report(map[string]executor.OperandState{
+		"opB": executor.OperandNotReady,
+	}))
+	assert.Equal(t, time.Second, wait)
+
+	// The backoff is reset once the operand succeeds.
+	b.update(podA, report(map[string]executor.OperandState{
+		"opB": executor.OperandSucceeded,
+	}))
+	wait, _ = b.update(podA, report(map[string]executor.OperandState{
+		"opB": executor.OperandNotReady,
+	}))
+	assert.Equal(t, time.Second, wait)
+
+	// The backoff of all the operands is reset once the object is forgotten.
+	b.forgetObject(podA)
+	assert.NotContains(t, b.operands, podA.GetUID())
+	assert.Contains(t, b.operands, podB.GetUID())
+	for _, name := range []string{"opB", "opC"} {
+		assert.Equal(t, 0, b.limiter.NumRequeues(backoffKey(podA, name)))
+	}
+	assert.Equal(t, 1, b.limiter.NumRequeues(backoffKey(podB, "opB")))
+}
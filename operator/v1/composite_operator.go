@@ -4,16 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/lru"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/go-logr/logr"
 	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/health"
 	"github.com/ondat/operator-toolkit/operator/v1/operand"
 	"github.com/ondat/operator-toolkit/operator/v1/playbook"
 	"github.com/ondat/operator-toolkit/operator/v1/playbook/order"
@@ -23,23 +30,63 @@ import (
 // defaultRetryPeriod is used for waiting before a retry.
 const defaultRetryPeriod = 5 * time.Second
 
+// defaultMaxBackoff is the default maximum wait period before retrying a not
+// ready operand.
+const defaultMaxBackoff = 5 * time.Minute
+
+// defaultPlaybookCacheSize is the default maximum number of cached playbooks.
+const defaultPlaybookCacheSize = 256
+
 // CompositeOperator contains all the operands and the relationship between
 // them. It implements the Operator interface.
 type CompositeOperator struct {
-	Operands          []operand.Operand
-	ensurePlaybook    *playbook.Playbook
-	cleanupPlaybook   *playbook.Playbook
+	// name is the name of the reconciler of the operator, used in the
+	// operand metrics.
+	name            string
+	Operands        []operand.Operand
+	ensurePlaybook  *playbook.Playbook
+	cleanupPlaybook *playbook.Playbook
+	ensureRequired  order.RequiredOperands
+	cleanupRequired order.RequiredOperands
+	// playbookCache caches the playbooks of the generated operand sets and
+	// of the subsets of the operands when some operands are disabled. The
+	// least recently used playbooks are evicted once the cache is full.
+	playbookCache     *lru.Cache
+	playbookCacheSize int
 	isSuspended       func(context.Context, client.Object) bool
 	executionStrategy executor.ExecutionStrategy
 	recorder          record.EventRecorder
 	executor          *executor.Executor
 	inst              *telemetry.Instrumentation
 	retryPeriod       time.Duration
+	operandTimeout    time.Duration
+	maxConcurrency    int
+	maxOperandLabels  int
+	backoffBase       time.Duration
+	backoffMax        time.Duration
+	backoff           *operandBackoff
+	operandsFunc      OperandsFunc
+	health            *health.Tracker
+	// previousOperands stores the operands of the parent objects in the
+	// last reconciliation, by UID, to clean up the removed operands.
+	previousOperands sync.Map
+	// forceRerun stores the last seen value of the force-rerun annotation
+	// of the parent objects, by UID.
+	forceRerun sync.Map
 }
 
 // CompositeOperatorOption is used to configure CompositeOperator.
 type CompositeOperatorOption func(*CompositeOperator)
 
+// WithName sets the name of the reconciler that runs the CompositeOperator.
+// It's used as the reconciler label of the operand metrics and should be the
+// name of the reconciler.
+func WithName(name string) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.name = name
+	}
+}
+
 // WithExecutionStrategy sets the execution strategy of a CompositeOperator.
 func WithExecutionStrategy(strategy executor.ExecutionStrategy) CompositeOperatorOption {
 	return func(c *CompositeOperator) {
@@ -54,6 +101,16 @@ func WithOperands(operands ...operand.Operand) CompositeOperatorOption {
 	}
 }
 
+// WithOperandsFunc sets a function that generates operands from the parent
+// object at reconcile time. The generated operands are executed with the
+// static operands and can depend on them. Generated operands that disappear
+// from one reconciliation to the next are cleaned up.
+func WithOperandsFunc(f OperandsFunc) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.operandsFunc = f
+	}
+}
+
 // SetIsSuspended can be used to set the operator suspension check.
 func WithSuspensionCheck(f func(context.Context, client.Object) bool) CompositeOperatorOption {
 	return func(c *CompositeOperator) {
@@ -76,6 +133,64 @@ func WithRetryPeriod(duration time.Duration) CompositeOperatorOption {
 	}
 }
 
+// WithOperandBackoff sets the base and the maximum wait period of the
+// exponential backoff of not ready operands. Each operand of a parent object
+// has its own backoff. The wait period doubles every time the operand is found
+// not ready and is reset once the operand is ready. By default, the base is
+// the retry period and the maximum is five minutes.
+func WithOperandBackoff(base, max time.Duration) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.backoffBase = base
+		c.backoffMax = max
+	}
+}
+
+// WithOperandTimeout sets the default timeout of an operand execution. It's
+// used for the operands that don't implement operand.Timeouter. A zero
+// timeout means no timeout.
+func WithOperandTimeout(timeout time.Duration) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.operandTimeout = timeout
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of operands executed
+// concurrently when the execution strategy is executor.BoundedParallel.
+func WithMaxConcurrency(n int) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.maxConcurrency = n
+	}
+}
+
+// WithMaxOperandLabels sets the maximum number of operand names used as the
+// operand label of the operand metrics. The operands past the limit share the
+// metrics.OperandOther label. It bounds the number of metric series of the
+// operands generated by the OperandsFunc, whose names usually vary per parent
+// object. The default is 100.
+func WithMaxOperandLabels(n int) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.maxOperandLabels = n
+	}
+}
+
+// WithPlaybookCacheSize sets the maximum number of playbooks cached for the
+// operand sets generated by the OperandsFunc and for the subsets of the
+// operands when some operands are disabled. The least recently used playbooks
+// are evicted once the cache is full. The default size is 256.
+func WithPlaybookCacheSize(size int) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.playbookCacheSize = size
+	}
+}
+
+// WithHealthTracker sets a health tracker that keeps the last health of the
+// operands of each parent object, updated after every ensure.
+func WithHealthTracker(t *health.Tracker) CompositeOperatorOption {
+	return func(c *CompositeOperator) {
+		c.health = t
+	}
+}
+
 // WithInstrumentation configures the instrumentation of the CompositeOperator.
 func WithInstrumentation(tp trace.TracerProvider, log logr.Logger) CompositeOperatorOption {
 	return func(c *CompositeOperator) {
@@ -112,25 +227,62 @@ func NewCompositeOperator(opts ...CompositeOperatorOption) (*CompositeOperator,
 		WithInstrumentation(nil, ctrl.Log)(c)
 	}
 
+	// Use the retry period as the base backoff of the operands by default.
+	if c.backoffBase <= 0 {
+		c.backoffBase = c.retryPeriod
+	}
+	if c.backoffMax <= 0 {
+		c.backoffMax = defaultMaxBackoff
+	}
+	c.backoff = newOperandBackoff(c.backoffBase, c.backoffMax)
+
+	if c.playbookCacheSize <= 0 {
+		c.playbookCacheSize = defaultPlaybookCacheSize
+	}
+	c.playbookCache = lru.New(c.playbookCacheSize)
+
 	var err error
 	// Initialize the operator ensure playbook.
-	c.ensurePlaybook, err = playbook.NewPlaybook(c.Operands, operand.Ensure)
+	c.ensureRequired, err = order.Required(c.Operands, operand.Ensure)
+	if err != nil {
+		return nil, err
+	}
+	c.ensurePlaybook, err = playbook.NewPlaybookFromRequired(c.Operands, c.ensureRequired, operand.Ensure)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize the operator cleanup Playbook.
-	c.cleanupPlaybook, err = playbook.NewPlaybook(c.Operands, operand.Cleanup)
+	c.cleanupRequired, err = order.Required(c.Operands, operand.Cleanup)
+	if err != nil {
+		return nil, err
+	}
+	c.cleanupPlaybook, err = playbook.NewPlaybookFromRequired(c.Operands, c.cleanupRequired, operand.Cleanup)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create an executor.
-	c.executor = executor.NewExecutor(c.executionStrategy, c.recorder)
+	c.executor = executor.NewExecutor(c.executionStrategy, c.recorder,
+		executor.WithName(c.name),
+		executor.WithOperandTimeout(c.operandTimeout),
+		executor.WithMaxConcurrency(c.maxConcurrency),
+		executor.WithMaxOperandLabels(c.maxOperandLabels),
+	)
 
 	return c, nil
 }
 
+// EnsurePlaybook returns the playbook of the static operands for ensure.
+func (co *CompositeOperator) EnsurePlaybook() *playbook.Playbook {
+	return co.ensurePlaybook
+}
+
+// CleanupPlaybook returns the playbook of the static operands for cleanup.
+func (co *CompositeOperator) CleanupPlaybook() *playbook.Playbook {
+	return co.cleanupPlaybook
+}
+
 // EnsureOrder returns the order at which the operands depends on each other
 // for creation of all the resources.
 func (co *CompositeOperator) EnsureOrder() order.OperandOrder {
@@ -155,6 +307,31 @@ func (co *CompositeOperator) CleanupBlockers() order.BlockingOperands {
 	return co.cleanupPlaybook.Blockers()
 }
 
+// ManagedKinds returns the kinds of the objects managed by the static
+// operands that implement operand.KindManager, sorted and without duplicates.
+// The operands generated by the OperandsFunc aren't known before
+// reconciliation and aren't included.
+func (co *CompositeOperator) ManagedKinds() []schema.GroupVersionKind {
+	seen := map[schema.GroupVersionKind]bool{}
+	kinds := []schema.GroupVersionKind{}
+	for _, op := range co.Operands {
+		km, ok := op.(operand.KindManager)
+		if !ok {
+			continue
+		}
+		for _, gvk := range km.ManagedKinds() {
+			if !seen[gvk] {
+				seen[gvk] = true
+				kinds = append(kinds, gvk)
+			}
+		}
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		return kinds[i].String() < kinds[j].String()
+	})
+	return kinds
+}
+
 // IsSuspend implements the Operator interface. It checks if the operator can
 // run or if it's suspended and shouldn't run.
 func (co *CompositeOperator) IsSuspended(ctx context.Context, obj client.Object) bool {
@@ -166,41 +343,138 @@ func (co *CompositeOperator) IsSuspended(ctx context.Context, obj client.Object)
 
 // Ensure implements the Operator interface. It runs all the operands, in the
 // order of their dependencies, to ensure all the operations the individual
-// operands perform.
-func (co *CompositeOperator) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (ctrl.Result, error) {
+// operands perform. Operands that implement operand.Enabler and are disabled
+// for the object are cleaned up instead. The execution can be paused or
+// limited to some operands through the control annotations of the object, see
+// executor.ParseControl. The returned ExecutionReport contains
+// the state of each of the operands. If the object implements
+// OperandStatusSetter, the operand statuses are also set in the object.
+func (co *CompositeOperator) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (ctrl.Result, *executor.ExecutionReport, error) {
 	ctx, span, log := co.inst.Start(ctx, "Ensure")
 	defer span.End()
 
 	result := ctrl.Result{}
+	report := &executor.ExecutionReport{}
 
 	if !co.IsSuspended(ctx, obj) {
-		res, err := co.executor.ExecuteOperands(co.EnsureOrder(), co.EnsureBlockers(), operand.CallEnsure, ctx, obj, ownerRef)
+		co.resetForcedBackoff(obj)
+		res, rep, err := co.ensure(ctx, obj, ownerRef)
+		setOperandStatuses(obj, rep)
+		if co.health != nil {
+			co.health.Update(obj, rep)
+		}
+		// Update the backoff of the operands and get the wait period of the
+		// not ready operands.
+		waitPeriod, found := co.backoff.update(obj, rep)
 		if err != nil {
 			// Not ready error shouldn't be propagated to the caller. Handle
 			// the error gracefully by returning a requeue result with a wait
 			// period. Set explicit requeue regardless of the returned result
 			// because an error was found.
 			if errors.Is(err, operand.ErrNotReady) {
-				log.Info("components not ready, retrying in a few seconds...", "waitPeriod", co.retryPeriod, "failure", err)
-				return ctrl.Result{Requeue: true, RequeueAfter: co.retryPeriod}, nil
+				if !found {
+					waitPeriod = co.retryPeriod
+				}
+				span.SetAttributes(attribute.Int64("wait-period", int64(waitPeriod)))
+				log.Info("components not ready, retrying after wait period", "waitPeriod", waitPeriod, "notReady", rep.InState(executor.OperandNotReady), "failure", err)
+				return ctrl.Result{Requeue: true, RequeueAfter: waitPeriod}, rep, nil
 			}
-			return ctrl.Result{Requeue: true}, err
+			return ctrl.Result{Requeue: true}, rep, err
 		}
 		result = res
+		report = rep
 		span.AddEvent("CompositeOperator Ensure executed successfully")
 	} else {
 		span.AddEvent("CompositeOperator Ensure skipped because it's suspended")
 	}
-	return result, nil
+	return result, report, nil
 }
 
-// Cleanup implements the Operator interface.
-func (co *CompositeOperator) Cleanup(ctx context.Context, obj client.Object) (result ctrl.Result, rerr error) {
+// Cleanup implements the Operator interface. The returned ExecutionReport
+// contains the state of each of the operands. If the object implements
+// OperandStatusSetter, the operand statuses are also set in the object.
+func (co *CompositeOperator) Cleanup(ctx context.Context, obj client.Object) (result ctrl.Result, report *executor.ExecutionReport, rerr error) {
 	ctx, span, _ := co.inst.Start(ctx, "Cleanup")
 	defer span.End()
 
+	report = &executor.ExecutionReport{}
+
 	if !co.IsSuspended(ctx, obj) {
-		return co.executor.ExecuteOperands(co.CleanupOrder(), co.CleanupBlockers(), operand.CallCleanup, ctx, obj, metav1.OwnerReference{})
+		var set *operandSet
+		result, report, set, rerr = co.cleanup(ctx, obj)
+		if set == nil {
+			return
+		}
+		setOperandStatuses(obj, report)
+		// Once the cleanup is complete, the state of the object is no
+		// longer needed.
+		if rerr == nil && result.IsZero() {
+			co.Forget(ctx, obj)
+		}
 	}
 	return
 }
+
+// Forget releases the state kept in memory about the given object. It's
+// called once the cleanup of the object is complete and implements the
+// composite controller Forgetter interface to also release the state of the
+// objects deleted without cleanup, like with the owner reference cleanup
+// strategy. Only the UID of the object is used.
+func (co *CompositeOperator) Forget(ctx context.Context, obj client.Object) {
+	co.backoff.forgetObject(obj)
+	co.previousOperands.Delete(obj.GetUID())
+	co.forceRerun.Delete(obj.GetUID())
+	if co.health != nil {
+		co.health.Forget(obj)
+	}
+}
+
+// cleanup runs the cleanup of the operand set of the object. With an
+// OperandsFunc, the operands removed since the previous reconciliation are
+// also cleaned up.
+func (co *CompositeOperator) cleanup(ctx context.Context, obj client.Object) (ctrl.Result, *executor.ExecutionReport, *operandSet, error) {
+	set, err := co.operandSetFor(ctx, obj)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, &executor.ExecutionReport{}, nil, err
+	}
+
+	result, report, err := co.executor.ExecuteOperands(set.cleanupPlaybook.Order(), set.cleanupPlaybook.Blockers(), operand.CallCleanup, operand.Cleanup, ctx, obj, metav1.OwnerReference{})
+	if co.operandsFunc != nil {
+		rRes, rRep, rErr := co.cleanupRemovedOperands(ctx, obj, set)
+		report.Merge(rRep)
+		result = mergeResults(result, rRes)
+		err = kerrors.NewAggregate([]error{err, rErr})
+	}
+	return result, report, set, err
+}
+
+// ensure runs the ensure of the operand set of the object. With an
+// OperandsFunc, the operands removed since the previous reconciliation are
+// cleaned up.
+func (co *CompositeOperator) ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (ctrl.Result, *executor.ExecutionReport, error) {
+	set, err := co.operandSetFor(ctx, obj)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, &executor.ExecutionReport{}, err
+	}
+
+	if co.operandsFunc == nil {
+		return co.ensureOperands(ctx, obj, ownerRef, set)
+	}
+
+	rRes, rRep, rErr := co.cleanupRemovedOperands(ctx, obj, set)
+	eRes, eRep, eErr := co.ensureOperands(ctx, obj, ownerRef, set)
+
+	report := &executor.ExecutionReport{}
+	report.Merge(eRep)
+	report.Merge(rRep)
+
+	return mergeResults(rRes, eRes), report, kerrors.NewAggregate([]error{rErr, eErr})
+}
+
+// setOperandStatuses sets the operand statuses from the given report in the
+// object if the object implements OperandStatusSetter.
+func setOperandStatuses(obj client.Object, report *executor.ExecutionReport) {
+	if setter, ok := obj.(OperandStatusSetter); ok {
+		setter.SetOperandStatuses(report.OperandStatuses())
+	}
+}
@@ -2,21 +2,33 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	tkctrl "github.com/ondat/operator-toolkit/controller"
 	eventv1 "github.com/ondat/operator-toolkit/event/v1"
 	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/health"
 	"github.com/ondat/operator-toolkit/operator/v1/operand"
 	"github.com/ondat/operator-toolkit/operator/v1/operand/mocks"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 // fooCreatedEvent is a ReconcilerEvent type used for testing event
@@ -249,7 +261,7 @@ func TestCompositeOperatorEnsure(t *testing.T) {
 			// Run ensure for the given number of times.
 			i := 0
 			for i < tc.times {
-				res, eerr = co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+				res, _, eerr = co.Ensure(context.Background(), pod, metav1.OwnerReference{})
 				assert.Nil(t, eerr)
 				i++
 			}
@@ -259,4 +271,717 @@ func TestCompositeOperatorEnsure(t *testing.T) {
 	}
 }
 
+// podWithOperands is a parent object that implements OperandStatusSetter.
+type podWithOperands struct {
+	corev1.Pod
+	operands []executor.OperandStatus
+}
+
+func (p *podWithOperands) SetOperandStatuses(s []executor.OperandStatus) {
+	p.operands = s
+}
+
+func TestCompositeOperatorEnsureReport(t *testing.T) {
+	someErr := errors.New("some error")
+
+	tests := []struct {
+		name         string
+		strategy     executor.ExecutionStrategy
+		expectations func(a, b, c *mocks.MockOperand)
+		wantErr      bool
+		wantStates   map[string]executor.OperandState
+	}{
+		{
+			name:     "all succeeded",
+			strategy: executor.Parallel,
+			expectations: func(opA, opB, opC *mocks.MockOperand) {
+				for _, op := range []*mocks.MockOperand{opA, opB, opC} {
+					op.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any())
+					op.EXPECT().RequeueStrategy().AnyTimes()
+					op.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+					op.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+				}
+			},
+			wantStates: map[string]executor.OperandState{
+				"opA": executor.OperandSucceeded,
+				"opB": executor.OperandSucceeded,
+				"opC": executor.OperandSucceeded,
+			},
+		},
+		{
+			name:     "blocking operand failed",
+			strategy: executor.Parallel,
+			expectations: func(opA, opB, opC *mocks.MockOperand) {
+				opA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, someErr)
+				opA.EXPECT().RequeueStrategy().AnyTimes()
+				opB.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any())
+				opB.EXPECT().RequeueStrategy().AnyTimes()
+				opB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+				opB.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantErr: true,
+			wantStates: map[string]executor.OperandState{
+				"opA": executor.OperandFailed,
+				"opB": executor.OperandSucceeded,
+				"opC": executor.OperandBlocked,
+			},
+		},
+		{
+			name:     "non-blocking operand not ready",
+			strategy: executor.Parallel,
+			expectations: func(opA, opB, opC *mocks.MockOperand) {
+				opA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any())
+				opA.EXPECT().RequeueStrategy().AnyTimes()
+				opA.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+				opA.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+				opB.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				opB.EXPECT().RequeueStrategy().AnyTimes()
+				opB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+				opC.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any())
+				opC.EXPECT().RequeueStrategy().AnyTimes()
+				opC.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+				opC.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantStates: map[string]executor.OperandState{
+				"opA": executor.OperandSucceeded,
+				"opB": executor.OperandNotReady,
+				"opC": executor.OperandSucceeded,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			mA := mocks.NewMockOperand(mctrl)
+			mB := mocks.NewMockOperand(mctrl)
+			mC := mocks.NewMockOperand(mctrl)
+
+			// A, B, C and C requires A.
+			mA.EXPECT().Name().Return("opA").AnyTimes()
+			mA.EXPECT().Requires().Return([]string{})
+			mA.EXPECT().CleanupRequires().Return([]string{})
+
+			mB.EXPECT().Name().Return("opB").AnyTimes()
+			mB.EXPECT().Requires().Return([]string{})
+			mB.EXPECT().CleanupRequires().Return([]string{})
+
+			mC.EXPECT().Name().Return("opC").AnyTimes()
+			mC.EXPECT().Requires().Return([]string{"opA"})
+			mC.EXPECT().CleanupRequires().Return([]string{})
+
+			tc.expectations(mA, mB, mC)
+
+			co, err := NewCompositeOperator(
+				WithEventRecorder(record.NewFakeRecorder(1)),
+				WithExecutionStrategy(tc.strategy),
+				WithOperands(mA, mB, mC),
+			)
+			assert.Nil(t, err)
+
+			parent := &podWithOperands{}
+			_, report, err := co.Ensure(context.Background(), parent, metav1.OwnerReference{})
+			assert.Equal(t, tc.wantErr, err != nil, "error: %v", err)
+
+			assert.Len(t, report.Operands, len(tc.wantStates))
+			for name, state := range tc.wantStates {
+				r := report.Get(name)
+				if assert.NotNil(t, r, "report of %s", name) {
+					assert.Equal(t, state, r.State, "state of %s", name)
+					assert.Equal(t, state == executor.OperandSucceeded, r.Ready, "ready of %s", name)
+				}
+			}
+
+			// The operand statuses are set in the parent.
+			assert.Len(t, parent.operands, len(tc.wantStates))
+			for _, s := range parent.operands {
+				assert.Equal(t, string(tc.wantStates[s.Name]), s.State, "status of %s", s.Name)
+			}
+		})
+	}
+}
+
+// timeoutOperand is an operand that implements operand.Timeouter.
+type timeoutOperand struct {
+	*mocks.MockOperand
+	timeout time.Duration
+}
+
+func (o *timeoutOperand) Timeout() time.Duration {
+	return o.timeout
+}
+
+func TestCompositeOperatorOperandTimeout(t *testing.T) {
+	// blockingEnsure blocks until the context is cancelled.
+	blockingEnsure := func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tests := []struct {
+		name           string
+		defaultTimeout time.Duration
+		opATimeout     time.Duration
+	}{
+		{
+			name:           "default timeout",
+			defaultTimeout: 50 * time.Millisecond,
+		},
+		{
+			name:       "operand timeout",
+			opATimeout: 50 * time.Millisecond,
+		},
+		{
+			name:           "operand timeout overrides default",
+			defaultTimeout: time.Hour,
+			opATimeout:     50 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			mA := mocks.NewMockOperand(mctrl)
+			mB := mocks.NewMockOperand(mctrl)
+
+			// A, B and B requires A.
+			mA.EXPECT().Name().Return("opA").AnyTimes()
+			mA.EXPECT().Requires().Return([]string{})
+			mA.EXPECT().CleanupRequires().Return([]string{})
+			mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(blockingEnsure)
+			mA.EXPECT().RequeueStrategy().AnyTimes()
+
+			mB.EXPECT().Name().Return("opB").AnyTimes()
+			mB.EXPECT().Requires().Return([]string{"opA"})
+			mB.EXPECT().CleanupRequires().Return([]string{})
+
+			var opA operand.Operand = mA
+			if tc.opATimeout > 0 {
+				opA = &timeoutOperand{MockOperand: mA, timeout: tc.opATimeout}
+			}
+
+			co, err := NewCompositeOperator(
+				WithEventRecorder(record.NewFakeRecorder(1)),
+				WithOperandTimeout(tc.defaultTimeout),
+				WithOperands(opA, mB),
+			)
+			assert.Nil(t, err)
+
+			start := time.Now()
+			_, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+			assert.Less(t, time.Since(start), 10*time.Second, "execution must stop after the timeout")
+
+			assert.True(t, errors.Is(err, operand.ErrTimeout), "expected timeout error, got: %v", err)
+
+			rA := report.Get("opA")
+			if assert.NotNil(t, rA) {
+				assert.Equal(t, executor.OperandFailed, rA.State)
+				assert.True(t, rA.TimedOut)
+			}
+			rB := report.Get("opB")
+			if assert.NotNil(t, rB) {
+				assert.Equal(t, executor.OperandBlocked, rB.State)
+			}
+		})
+	}
+}
+
+func TestCompositeOperatorOperandInFlight(t *testing.T) {
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	mA := mocks.NewMockOperand(mctrl)
+
+	// The first execution of opA ignores the context cancellation and runs
+	// until released.
+	release := make(chan struct{})
+	stuckEnsure := func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+		<-release
+		return nil, nil
+	}
+
+	mA.EXPECT().Name().Return("opA").AnyTimes()
+	mA.EXPECT().Requires().Return([]string{})
+	mA.EXPECT().CleanupRequires().Return([]string{})
+	mA.EXPECT().RequeueStrategy().AnyTimes()
+	gomock.InOrder(
+		mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(stuckEnsure),
+		mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()),
+	)
+	// Both executions check the readiness, the result of the first one is
+	// discarded.
+	mA.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+	mA.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(1)),
+		WithOperandTimeout(50*time.Millisecond),
+		WithOperands(mA),
+	)
+	assert.Nil(t, err)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pod-uid"}}
+	_, _, err = co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+	assert.True(t, errors.Is(err, operand.ErrTimeout), "expected timeout error, got: %v", err)
+
+	// opA isn't executed again while its first execution is running.
+	_, report, err := co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+	assert.True(t, errors.Is(err, operand.ErrInFlight), "expected in-flight error, got: %v", err)
+	assert.Equal(t, executor.OperandFailed, report.Get("opA").State)
+
+	// opA is executed again once its first execution returns.
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, report, err = co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+		return !errors.Is(err, operand.ErrInFlight)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, executor.OperandSucceeded, report.Get("opA").State)
+}
+
+func TestCompositeOperatorOperandPanic(t *testing.T) {
+	panickingEnsure := func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+		panic("boom")
+	}
+
+	tests := []struct {
+		name string
+		opts []CompositeOperatorOption
+	}{
+		{
+			name: "serial",
+			opts: []CompositeOperatorOption{WithExecutionStrategy(executor.Serial)},
+		},
+		{
+			name: "parallel",
+			opts: []CompositeOperatorOption{WithExecutionStrategy(executor.Parallel)},
+		},
+		{
+			name: "bounded parallel",
+			opts: []CompositeOperatorOption{WithExecutionStrategy(executor.BoundedParallel)},
+		},
+		{
+			name: "with timeout",
+			opts: []CompositeOperatorOption{WithOperandTimeout(time.Minute)},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mctrl := gomock.NewController(t)
+			defer mctrl.Finish()
+			mA := mocks.NewMockOperand(mctrl)
+			mB := mocks.NewMockOperand(mctrl)
+
+			// A, B and B requires A.
+			mA.EXPECT().Name().Return("panic-opA").AnyTimes()
+			mA.EXPECT().Requires().Return([]string{})
+			mA.EXPECT().CleanupRequires().Return([]string{})
+			mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(panickingEnsure)
+			mA.EXPECT().RequeueStrategy().AnyTimes()
+
+			mB.EXPECT().Name().Return("panic-opB").AnyTimes()
+			mB.EXPECT().Requires().Return([]string{"panic-opA"})
+			mB.EXPECT().CleanupRequires().Return([]string{})
+
+			recorder := record.NewFakeRecorder(10)
+			panics := metrics.Panics.WithLabelValues(metrics.ComponentOperand, "panic-test")
+			failures := metrics.OperandFailures.WithLabelValues("panic-test", "panic-opA", metrics.CallEnsure, "panic")
+			beforePanics, beforeFailures := testutil.ToFloat64(panics), testutil.ToFloat64(failures)
+
+			co, err := NewCompositeOperator(
+				append(tc.opts, WithName("panic-test"), WithEventRecorder(recorder), WithOperands(mA, mB))...,
+			)
+			assert.Nil(t, err)
+
+			result, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+			assert.True(t, tkctrl.IsPanic(err), "expected panic error, got: %v", err)
+			assert.True(t, result.Requeue)
+
+			rA := report.Get("panic-opA")
+			if assert.NotNil(t, rA) {
+				assert.Equal(t, executor.OperandFailed, rA.State)
+			}
+			rB := report.Get("panic-opB")
+			if assert.NotNil(t, rB) {
+				assert.Equal(t, executor.OperandBlocked, rB.State)
+			}
+
+			assert.Equal(t, beforePanics+1, testutil.ToFloat64(panics))
+			assert.Equal(t, beforeFailures+1, testutil.ToFloat64(failures))
+			if assert.Len(t, recorder.Events, 1) {
+				assert.Contains(t, <-recorder.Events, executor.ReasonOperandPanic)
+			}
+		})
+	}
+}
+
+func TestCompositeOperatorSerialBlocking(t *testing.T) {
+	someErr := errors.New("some error")
+
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	mA := mocks.NewMockOperand(mctrl)
+	mB := mocks.NewMockOperand(mctrl)
+	mC := mocks.NewMockOperand(mctrl)
+
+	// A, B, C and C requires B.
+	// Order: [opA opB] [opC]
+	mA.EXPECT().Name().Return("opA").AnyTimes()
+	mA.EXPECT().Requires().Return([]string{})
+	mA.EXPECT().CleanupRequires().Return([]string{})
+	mA.EXPECT().RequeueStrategy().AnyTimes()
+	mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, someErr)
+
+	mB.EXPECT().Name().Return("opB").AnyTimes()
+	mB.EXPECT().Requires().Return([]string{})
+	mB.EXPECT().CleanupRequires().Return([]string{})
+	mB.EXPECT().RequeueStrategy().AnyTimes()
+
+	mC.EXPECT().Name().Return("opC").AnyTimes()
+	mC.EXPECT().Requires().Return([]string{"opB"})
+	mC.EXPECT().CleanupRequires().Return([]string{})
+	mC.EXPECT().RequeueStrategy().AnyTimes()
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(1)),
+		WithExecutionStrategy(executor.Serial),
+		WithOperands(mA, mB, mC),
+	)
+	assert.Nil(t, err)
+
+	// The failure of the non-blocking opA stops its step before the blocking
+	// opB runs. opB then blocks opC, which requires it.
+	_, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+	assert.ErrorIs(t, err, someErr)
+	assert.Equal(t, executor.OperandFailed, report.Get("opA").State)
+	assert.Equal(t, executor.OperandBlocked, report.Get("opB").State)
+	assert.Equal(t, executor.OperandBlocked, report.Get("opC").State)
+}
+
+func TestCompositeOperatorBoundedParallel(t *testing.T) {
+	someErr := errors.New("some error")
+
+	// blockingEnsure blocks until the context is cancelled.
+	blockingEnsure := func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+
+		var running, maxRunning int32
+		countingEnsure := func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		}
+
+		ops := []operand.Operand{}
+		for i := 0; i < 6; i++ {
+			m := mocks.NewMockOperand(mctrl)
+			m.EXPECT().Name().Return(fmt.Sprintf("op%d", i)).AnyTimes()
+			m.EXPECT().Requires().Return([]string{})
+			m.EXPECT().CleanupRequires().Return([]string{})
+			m.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(countingEnsure)
+			m.EXPECT().RequeueStrategy().AnyTimes()
+			m.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+			m.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+			ops = append(ops, m)
+		}
+
+		co, err := NewCompositeOperator(
+			WithEventRecorder(record.NewFakeRecorder(1)),
+			WithExecutionStrategy(executor.BoundedParallel),
+			WithMaxConcurrency(2),
+			WithOperands(ops...),
+		)
+		assert.Nil(t, err)
+
+		_, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+		assert.Nil(t, err)
+		assert.Len(t, report.InState(executor.OperandSucceeded), 6)
+		assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(2), "max concurrent operands")
+	})
+
+	t.Run("fail fast on blocking operand failure", func(t *testing.T) {
+		mctrl := gomock.NewController(t)
+		defer mctrl.Finish()
+		mA := mocks.NewMockOperand(mctrl)
+		mB := mocks.NewMockOperand(mctrl)
+		mC := mocks.NewMockOperand(mctrl)
+		mD := mocks.NewMockOperand(mctrl)
+
+		// A, B, C and D requires A. A is a blocking operand.
+		mA.EXPECT().Name().Return("opA").AnyTimes()
+		mA.EXPECT().Requires().Return([]string{})
+		mA.EXPECT().CleanupRequires().Return([]string{})
+		mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, someErr)
+		mA.EXPECT().RequeueStrategy().AnyTimes()
+
+		// B and C never finish unless cancelled.
+		for _, m := range []*mocks.MockOperand{mB, mC} {
+			m.EXPECT().Requires().Return([]string{})
+			m.EXPECT().CleanupRequires().Return([]string{})
+			m.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(blockingEnsure).AnyTimes()
+			m.EXPECT().RequeueStrategy().AnyTimes()
+		}
+		mB.EXPECT().Name().Return("opB").AnyTimes()
+		mC.EXPECT().Name().Return("opC").AnyTimes()
+
+		mD.EXPECT().Name().Return("opD").AnyTimes()
+		mD.EXPECT().Requires().Return([]string{"opA"})
+		mD.EXPECT().CleanupRequires().Return([]string{})
+
+		co, err := NewCompositeOperator(
+			WithEventRecorder(record.NewFakeRecorder(1)),
+			WithExecutionStrategy(executor.BoundedParallel),
+			WithMaxConcurrency(3),
+			WithOperands(mA, mB, mC, mD),
+		)
+		assert.Nil(t, err)
+
+		_, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+		assert.True(t, errors.Is(err, someErr), "expected operand error, got: %v", err)
+
+		assert.Equal(t, []string{"opA"}, report.InState(executor.OperandFailed))
+		assert.ElementsMatch(t, []string{"opB", "opC", "opD"}, report.InState(executor.OperandBlocked))
+	})
+}
+
+func TestCompositeOperatorEnsureBackoff(t *testing.T) {
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	mA := mocks.NewMockOperand(mctrl)
+	mB := mocks.NewMockOperand(mctrl)
+
+	mA.EXPECT().Name().Return("opA").AnyTimes()
+	mA.EXPECT().Requires().Return([]string{})
+	mA.EXPECT().CleanupRequires().Return([]string{})
+	mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mA.EXPECT().RequeueStrategy().AnyTimes()
+	mA.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	mA.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// opB is not ready for the first three executions.
+	mB.EXPECT().Name().Return("opB").AnyTimes()
+	mB.EXPECT().Requires().Return([]string{})
+	mB.EXPECT().CleanupRequires().Return([]string{})
+	mB.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mB.EXPECT().RequeueStrategy().AnyTimes()
+	gomock.InOrder(
+		mB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(false, nil).Times(3),
+		mB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil),
+		mB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(false, nil),
+	)
+	mB.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(1)),
+		WithOperandBackoff(time.Second, 3*time.Second),
+		WithOperands(mA, mB),
+	)
+	assert.Nil(t, err)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pod-uid"}}
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 0, time.Second} {
+		res, _, err := co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+		assert.Nil(t, err)
+		assert.Equal(t, want, res.RequeueAfter)
+	}
+
+	// The backoff is forgotten once the object is deleted.
+	co.Forget(context.Background(), pod)
+	assert.Equal(t, 0, co.backoff.limiter.NumRequeues(backoffKey(pod, "opB")))
+	assert.Empty(t, co.backoff.operands)
+}
+
+// enablerOperand is an operand that implements operand.Enabler.
+type enablerOperand struct {
+	*mocks.MockOperand
+	enabled *bool
+}
+
+func (o *enablerOperand) Enabled(ctx context.Context, obj client.Object) bool {
+	return *o.enabled
+}
+
+func TestCompositeOperatorConditionalOperands(t *testing.T) {
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+	mA := mocks.NewMockOperand(mctrl)
+	mB := mocks.NewMockOperand(mctrl)
+	mC := mocks.NewMockOperand(mctrl)
+
+	// A, B, C and C requires B. B is optional.
+	mA.EXPECT().Name().Return("opA").AnyTimes()
+	mA.EXPECT().Requires().Return([]string{})
+	mA.EXPECT().CleanupRequires().Return([]string{})
+
+	mB.EXPECT().Name().Return("opB").AnyTimes()
+	mB.EXPECT().Requires().Return([]string{})
+	mB.EXPECT().CleanupRequires().Return([]string{"opC"})
+
+	mC.EXPECT().Name().Return("opC").AnyTimes()
+	mC.EXPECT().Requires().Return([]string{"opB"})
+	mC.EXPECT().CleanupRequires().Return([]string{})
+
+	for _, op := range []*mocks.MockOperand{mA, mC} {
+		op.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+		op.EXPECT().RequeueStrategy().AnyTimes()
+		op.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil).Times(3)
+		op.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+	}
+
+	// B is disabled, enabled and disabled again.
+	mB.EXPECT().RequeueStrategy().AnyTimes()
+	gomock.InOrder(
+		mB.EXPECT().Delete(gomock.Any(), gomock.Any()),
+		mB.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()),
+		mB.EXPECT().Delete(gomock.Any(), gomock.Any()),
+	)
+	mB.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(true, nil)
+	mB.EXPECT().PostReady(gomock.Any(), gomock.Any()).Return(nil)
+
+	enabled := false
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(1)),
+		WithOperands(mA, &enablerOperand{MockOperand: mB, enabled: &enabled}, mC),
+	)
+	assert.Nil(t, err)
+
+	for _, e := range []bool{false, true, false} {
+		enabled = e
+		_, report, err := co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+		assert.Nil(t, err)
+
+		wantB := executor.OperandDisabled
+		if e {
+			wantB = executor.OperandSucceeded
+		}
+		assert.Len(t, report.Operands, 3)
+		assert.Equal(t, wantB, report.Get("opB").State)
+		assert.Equal(t, executor.OperandSucceeded, report.Get("opA").State)
+		assert.Equal(t, executor.OperandSucceeded, report.Get("opC").State)
+	}
+}
+
 // TODO: Add TestCompositeOperatorCleanup.
+
+func TestCompositeOperatorHealthTracker(t *testing.T) {
+	tracker := health.NewTracker()
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(
+			&fakeOperand{name: "opA", rec: &callRecorder{}},
+			&fakeOperand{name: "opB", requires: []string{"opA"}, rec: &callRecorder{}},
+		),
+		WithHealthTracker(tracker),
+	)
+	assert.Nil(t, err)
+
+	obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"}}
+
+	_, _, err = co.Ensure(context.Background(), obj, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	objects := tracker.Objects()
+	assert.Len(t, objects, 1)
+	assert.True(t, objects[0].Healthy)
+	assert.Len(t, objects[0].Operands, 2)
+	assert.Nil(t, tracker.Checker(nil))
+
+	// The health is forgotten once the cleanup is complete.
+	_, _, err = co.Cleanup(context.Background(), obj)
+	assert.Nil(t, err)
+	assert.Empty(t, tracker.Objects())
+
+	// The health is forgotten when the object is deleted without cleanup.
+	_, _, err = co.Ensure(context.Background(), obj, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	assert.Len(t, tracker.Objects(), 1)
+	co.Forget(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "foo-uid"}})
+	assert.Empty(t, tracker.Objects())
+}
+
+func TestCompositeOperatorManagedKinds(t *testing.T) {
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	secret := func(ctx context.Context, parent client.Object) (*corev1.Secret, error) {
+		return &corev1.Secret{}, nil
+	}
+	configMap := func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{}, nil
+	}
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(
+			operand.NewResourceOperand("secret", cli, secret),
+			operand.NewResourceOperand("configmapA", cli, configMap),
+			operand.NewResourceOperand("configmapB", cli, configMap),
+			// Operands that don't declare their kinds are ignored.
+			&fakeOperand{name: "other", rec: &callRecorder{}},
+		),
+	)
+	assert.Nil(t, err)
+
+	want := []schema.GroupVersionKind{
+		corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		corev1.SchemeGroupVersion.WithKind("Secret"),
+	}
+	assert.Equal(t, want, co.ManagedKinds())
+}
+
+func TestCompositeOperatorMetrics(t *testing.T) {
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+
+	mA := mocks.NewMockOperand(mctrl)
+	mA.EXPECT().Name().Return("metrics-opA").AnyTimes()
+	mA.EXPECT().Requires().Return([]string{})
+	mA.EXPECT().CleanupRequires().Return([]string{})
+	mA.EXPECT().RequeueStrategy().AnyTimes()
+	mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("boom"))
+	mA.EXPECT().Ensure(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	mA.EXPECT().ReadyCheck(gomock.Any(), gomock.Any()).Return(false, nil)
+	mA.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	co, err := NewCompositeOperator(
+		WithName("metrics-test"),
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(mA),
+	)
+	assert.Nil(t, err)
+
+	obj := &corev1.Pod{}
+	_, _, err = co.Ensure(context.Background(), obj, metav1.OwnerReference{})
+	assert.NotNil(t, err)
+	_, _, err = co.Ensure(context.Background(), obj, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	_, _, err = co.Cleanup(context.Background(), obj)
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.OperandFailures.WithLabelValues("metrics-test", "metrics-opA", metrics.CallEnsure, "other")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.OperandNotReady.WithLabelValues("metrics-test", "metrics-opA")))
+	assert.Equal(t, uint64(2), histogramCount(t, metrics.OperandDuration.WithLabelValues("metrics-test", "metrics-opA", metrics.CallEnsure)))
+	assert.Equal(t, uint64(1), histogramCount(t, metrics.OperandDuration.WithLabelValues("metrics-test", "metrics-opA", metrics.CallDelete)))
+}
+
+// histogramCount returns the number of observations of a histogram.
+func histogramCount(t *testing.T, o prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	assert.Nil(t, o.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
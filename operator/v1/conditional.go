@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook"
+)
+
+// ensureOperands runs the ensure of the enabled operands. If any operand is
+// disabled for the object, the disabled operands are cleaned up first, in
+// their cleanup order, and the enabled operands are ensured without waiting
+// for the disabled operands.
+func (co *CompositeOperator) ensureOperands(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference, set *operandSet) (ctrl.Result, *executor.ExecutionReport, error) {
+	ctx, span, _ := co.inst.Start(ctx, "ensureOperands")
+	defer span.End()
+
+	disabled := disabledOperands(ctx, obj, set.operands)
+	if len(disabled) == 0 {
+		return co.executor.ExecuteOperands(set.ensurePlaybook.Order(), set.ensurePlaybook.Blockers(), operand.CallEnsure, operand.Ensure, ctx, obj, ownerRef)
+	}
+
+	span.AddEvent("Found disabled operands")
+
+	ensurePB, cleanupPB, err := co.subsetPlaybooks(set, disabled)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, &executor.ExecutionReport{}, err
+	}
+
+	// Clean up the disabled operands.
+	cRes, cRep, cErr := co.executor.ExecuteOperands(cleanupPB.Order(), cleanupPB.Blockers(), operand.CallCleanup, operand.Cleanup, ctx, obj, metav1.OwnerReference{})
+	for i := range cRep.Operands {
+		if cRep.Operands[i].State == executor.OperandSucceeded {
+			cRep.Operands[i].State = executor.OperandDisabled
+			cRep.Operands[i].Ready = false
+		}
+	}
+
+	// Ensure the enabled operands.
+	eRes, eRep, eErr := co.executor.ExecuteOperands(ensurePB.Order(), ensurePB.Blockers(), operand.CallEnsure, operand.Ensure, ctx, obj, ownerRef)
+
+	report := &executor.ExecutionReport{}
+	report.Merge(eRep)
+	report.Merge(cRep)
+
+	return mergeResults(cRes, eRes), report, kerrors.NewAggregate([]error{cErr, eErr})
+}
+
+// disabledOperands returns the names of the given operands that implement
+// operand.Enabler and are disabled for the given object.
+func disabledOperands(ctx context.Context, obj client.Object, operands []operand.Operand) map[string]bool {
+	disabled := map[string]bool{}
+	for _, op := range operands {
+		if e, ok := op.(operand.Enabler); ok && !e.Enabled(ctx, obj) {
+			disabled[op.Name()] = true
+		}
+	}
+	return disabled
+}
+
+// playbookPair is a pair of playbooks, one to ensure the enabled operands
+// and the other to clean up the disabled operands.
+type playbookPair struct {
+	ensure  *playbook.Playbook
+	cleanup *playbook.Playbook
+}
+
+// subsetPlaybooks returns the playbooks to ensure the enabled operands and
+// to clean up the disabled operands of the given operand set. The playbooks
+// are cached by the operand set and the disabled operands.
+func (co *CompositeOperator) subsetPlaybooks(set *operandSet, disabled map[string]bool) (*playbook.Playbook, *playbook.Playbook, error) {
+	names := make([]string, 0, len(disabled))
+	for name := range disabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := set.key + "/disabled:" + strings.Join(names, ",")
+
+	if v, ok := co.playbookCache.Get(key); ok {
+		pbs := v.(playbookPair)
+		return pbs.ensure.WithOperands(set.operands), pbs.cleanup.WithOperands(set.operands), nil
+	}
+
+	enabled := map[string]bool{}
+	enabledOps := []operand.Operand{}
+	disabledOps := []operand.Operand{}
+	for _, op := range set.operands {
+		if disabled[op.Name()] {
+			disabledOps = append(disabledOps, op)
+		} else {
+			enabled[op.Name()] = true
+			enabledOps = append(enabledOps, op)
+		}
+	}
+
+	ensurePB, err := playbook.NewPlaybookFromRequired(enabledOps, set.ensureRequired.Without(disabled), operand.Ensure)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanupPB, err := playbook.NewPlaybookFromRequired(disabledOps, set.cleanupRequired.Without(enabled), operand.Cleanup)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	co.playbookCache.Add(key, playbookPair{ensure: ensurePB, cleanup: cleanupPB})
+	return ensurePB, cleanupPB, nil
+}
+
+// mergeResults merges the given results. The merged result requeues if any
+// of the results requeues, after the shortest non-zero wait period.
+func mergeResults(results ...ctrl.Result) ctrl.Result {
+	merged := ctrl.Result{}
+	for _, r := range results {
+		merged.Requeue = merged.Requeue || r.Requeue
+		if r.RequeueAfter > 0 && (merged.RequeueAfter == 0 || r.RequeueAfter < merged.RequeueAfter) {
+			merged.RequeueAfter = r.RequeueAfter
+		}
+	}
+	return merged
+}
@@ -0,0 +1,27 @@
+package v1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+// resetForcedBackoff resets the backoff of the operands forced to run by the
+// force-rerun annotation of the object when the value of the annotation
+// changes. The last seen value is stored by the UID of the object.
+func (co *CompositeOperator) resetForcedBackoff(obj client.Object) {
+	value, ok := obj.GetAnnotations()[executor.AnnotationForceRerun]
+	if !ok {
+		co.forceRerun.Delete(obj.GetUID())
+		return
+	}
+	if prev, found := co.forceRerun.Load(obj.GetUID()); found && prev.(string) == value {
+		return
+	}
+	co.forceRerun.Store(obj.GetUID(), value)
+
+	control, _ := executor.ParseControl(obj)
+	for name := range control.ForceRerun {
+		co.backoff.forget(obj, name)
+	}
+}
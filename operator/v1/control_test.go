@@ -0,0 +1,344 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	compositev1 "github.com/ondat/operator-toolkit/controller/composite/v1"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	tdv1alpha1 "github.com/ondat/operator-toolkit/testdata/api/v1alpha1"
+)
+
+func TestCompositeOperatorControlAnnotations(t *testing.T) {
+	// A, B, C requires A, D requires C.
+	// Order: [A B] [C] [D]
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		wantEnsured []string
+		wantStates  map[string]executor.OperandState
+		wantEvents  []string
+	}{
+		{
+			name:        "no annotations",
+			wantEnsured: []string{"A", "B", "C", "D"},
+		},
+		{
+			name:        "pause after first step",
+			annotations: map[string]string{executor.AnnotationPauseAfterStep: "0"},
+			wantEnsured: []string{"A", "B"},
+			wantStates: map[string]executor.OperandState{
+				"C": executor.OperandPaused,
+				"D": executor.OperandPaused,
+			},
+			wantEvents: []string{"Normal ExecutionPaused Execution paused after step 0, operands not executed: C, D"},
+		},
+		{
+			name:        "skip",
+			annotations: map[string]string{executor.AnnotationSkip: "B,C"},
+			wantEnsured: []string{"A", "D"},
+			wantStates: map[string]executor.OperandState{
+				"B": executor.OperandSkipped,
+				"C": executor.OperandSkipped,
+			},
+			wantEvents: []string{"Normal OperandsSkipped Skipped operands: B, C"},
+		},
+		{
+			name: "run only with forced operand",
+			annotations: map[string]string{
+				executor.AnnotationRunOnly:    "C",
+				executor.AnnotationForceRerun: "A",
+			},
+			wantEnsured: []string{"A", "C"},
+			wantStates: map[string]executor.OperandState{
+				"B": executor.OperandSkipped,
+				"D": executor.OperandSkipped,
+			},
+			wantEvents: []string{
+				"Normal OperandsSkipped Skipped operands: B, D",
+				"Normal OperandsForced Forced run of operands: A",
+			},
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{executor.AnnotationPauseAfterStep: "first"},
+			wantEnsured: []string{"A", "B", "C", "D"},
+			wantEvents:  []string{"Warning InvalidControlAnnotation invalid " + executor.AnnotationPauseAfterStep},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &callRecorder{}
+			recorder := record.NewFakeRecorder(10)
+			co, err := NewCompositeOperator(
+				WithEventRecorder(recorder),
+				WithOperands(
+					&fakeOperand{name: "A", rec: rec},
+					&fakeOperand{name: "B", rec: rec},
+					&fakeOperand{name: "C", requires: []string{"A"}, rec: rec},
+					&fakeOperand{name: "D", requires: []string{"C"}, rec: rec},
+				),
+			)
+			assert.Nil(t, err)
+
+			obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			_, report, err := co.Ensure(context.Background(), obj, metav1.OwnerReference{})
+			assert.Nil(t, err)
+
+			ensured, _ := rec.reset()
+			assert.Equal(t, tc.wantEnsured, ensured)
+			assert.Len(t, report.Operands, 4)
+			for name, state := range tc.wantStates {
+				assert.Equal(t, state, report.Get(name).State, name)
+			}
+
+			close(recorder.Events)
+			events := []string{}
+			for e := range recorder.Events {
+				events = append(events, e)
+			}
+			assert.Len(t, events, len(tc.wantEvents))
+			for i := range tc.wantEvents {
+				if i < len(events) {
+					assert.True(t, strings.HasPrefix(events[i], tc.wantEvents[i]), events[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompositeOperatorForceRerunResetsBackoff(t *testing.T) {
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(&fakeOperand{name: "A", rec: &callRecorder{}}),
+	)
+	assert.Nil(t, err)
+
+	obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "uid-1"}}
+	key := backoffKey(obj, "A")
+	co.backoff.limiter.When(key)
+	co.backoff.limiter.When(key)
+
+	// The backoff is reset when the annotation is set.
+	obj.SetAnnotations(map[string]string{executor.AnnotationForceRerun: "A"})
+	co.resetForcedBackoff(obj)
+	assert.Equal(t, 0, co.backoff.limiter.NumRequeues(key))
+
+	// The backoff is not reset again until the value changes.
+	co.backoff.limiter.When(key)
+	co.resetForcedBackoff(obj)
+	assert.Equal(t, 1, co.backoff.limiter.NumRequeues(key))
+
+	obj.SetAnnotations(map[string]string{executor.AnnotationForceRerun: "A,B"})
+	co.resetForcedBackoff(obj)
+	assert.Equal(t, 0, co.backoff.limiter.NumRequeues(key))
+
+	// The last seen value is forgotten once the object is deleted.
+	co.Forget(context.Background(), obj)
+	_, found := co.forceRerun.Load(obj.GetUID())
+	assert.False(t, found)
+}
+
+// operatorController is a composite controller that runs a
+// CompositeOperator.
+type operatorController struct {
+	co *CompositeOperator
+}
+
+var _ compositev1.Controller = &operatorController{}
+
+func (c *operatorController) Default(context.Context, client.Object)        {}
+func (c *operatorController) Validate(context.Context, client.Object) error { return nil }
+func (c *operatorController) UpdateStatus(context.Context, client.Object) error {
+	return nil
+}
+
+func (c *operatorController) Initialize(ctx context.Context, obj client.Object, condn metav1.Condition) error {
+	obj.(*tdv1alpha1.Game).Status.Conditions = []metav1.Condition{condn}
+	return nil
+}
+
+func (c *operatorController) Operate(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+	result, _, err := c.co.Ensure(ctx, obj, metav1.OwnerReference{})
+	return result, err
+}
+
+func (c *operatorController) Cleanup(ctx context.Context, obj client.Object) (ctrl.Result, error) {
+	result, _, err := c.co.Cleanup(ctx, obj)
+	return result, err
+}
+
+func TestCompositeOperatorControlAnnotationsCleanup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	const finalizer = "test-finalizer"
+	key := types.NamespacedName{Name: "test-game", Namespace: "test-ns"}
+
+	// A, B, C, D with the cleanup of A requiring C and the cleanup of C
+	// requiring D.
+	// Cleanup order: [B D] [C] [A]
+	cases := []struct {
+		name          string
+		annotations   map[string]string
+		wantDeleted   []string
+		wantFinalizer bool
+	}{
+		{
+			name:        "no annotations",
+			wantDeleted: []string{"A", "B", "C", "D"},
+		},
+		{
+			name:          "paused",
+			annotations:   map[string]string{executor.AnnotationPauseAfterStep: "0"},
+			wantDeleted:   []string{"B", "D"},
+			wantFinalizer: true,
+		},
+		{
+			name:          "skipped",
+			annotations:   map[string]string{executor.AnnotationSkip: "C"},
+			wantDeleted:   []string{"A", "B", "D"},
+			wantFinalizer: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &callRecorder{}
+			co, err := NewCompositeOperator(
+				WithEventRecorder(record.NewFakeRecorder(10)),
+				WithOperands(
+					&fakeOperand{name: "A", cleanupRequires: []string{"C"}, rec: rec},
+					&fakeOperand{name: "B", rec: rec},
+					&fakeOperand{name: "C", cleanupRequires: []string{"D"}, rec: rec},
+					&fakeOperand{name: "D", rec: rec},
+				),
+			)
+			assert.Nil(t, err)
+
+			now := metav1.Now()
+			game := &tdv1alpha1.Game{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              key.Name,
+					Namespace:         key.Namespace,
+					Annotations:       tc.annotations,
+					Finalizers:        []string{finalizer},
+					DeletionTimestamp: &now,
+				},
+				Status: tdv1alpha1.GameStatus{
+					Conditions: []metav1.Condition{compositev1.DefaultInitCondition},
+				},
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(game).Build()
+
+			cr := &compositev1.CompositeReconciler{}
+			assert.Nil(t, cr.Init(nil, &operatorController{co: co}, &tdv1alpha1.Game{},
+				compositev1.WithScheme(scheme),
+				compositev1.WithClient(cli),
+				compositev1.WithFinalizer(finalizer),
+				compositev1.WithCleanupStrategy(compositev1.FinalizerCleanup),
+			))
+
+			_, err = cr.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+			_, deleted := rec.reset()
+			assert.ElementsMatch(t, tc.wantDeleted, deleted)
+
+			got := &tdv1alpha1.Game{}
+			getErr := cli.Get(context.Background(), key, got)
+			if tc.wantFinalizer {
+				assert.True(t, errors.Is(err, executor.ErrCleanupNotExecuted), "expected cleanup error, got: %v", err)
+				assert.Nil(t, getErr)
+				assert.Equal(t, []string{finalizer}, got.GetFinalizers())
+			} else {
+				assert.Nil(t, err)
+				if getErr == nil {
+					assert.Empty(t, got.GetFinalizers())
+				} else {
+					assert.True(t, apierrors.IsNotFound(getErr))
+				}
+			}
+		})
+	}
+}
+
+func TestCompositeOperatorControlAnnotationsSkipUnchangedGeneration(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, tdv1alpha1.AddToScheme(scheme))
+
+	key := types.NamespacedName{Name: "test-game", Namespace: "test-ns"}
+	game := &tdv1alpha1.Game{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       key.Name,
+			Namespace:  key.Namespace,
+			UID:        "game-uid",
+			Generation: 1,
+		},
+		Status: tdv1alpha1.GameStatus{
+			Conditions: []metav1.Condition{compositev1.DefaultInitCondition},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(game).Build()
+
+	rec := &callRecorder{}
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(
+			&fakeOperand{name: "A", rec: rec},
+			&fakeOperand{name: "B", requires: []string{"A"}, rec: rec},
+		),
+	)
+	assert.Nil(t, err)
+
+	cr := &compositev1.CompositeReconciler{}
+	assert.Nil(t, cr.Init(nil, &operatorController{co: co}, &tdv1alpha1.Game{},
+		compositev1.WithScheme(scheme),
+		compositev1.WithClient(cli),
+		compositev1.WithSkipUnchangedGeneration(),
+	))
+
+	ctx := context.Background()
+	reconcile := func() []string {
+		_, err := cr.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		assert.Nil(t, err)
+		ensured, _ := rec.reset()
+		return ensured
+	}
+	setAnnotations := func(annotations map[string]string) {
+		got := &tdv1alpha1.Game{}
+		assert.Nil(t, cli.Get(ctx, key, got))
+		got.SetAnnotations(annotations)
+		assert.Nil(t, cli.Update(ctx, got))
+	}
+
+	assert.Equal(t, []string{"A", "B"}, reconcile())
+
+	// The reconciled generation is skipped.
+	assert.Empty(t, reconcile())
+
+	// Setting a control annotation doesn't change the generation but the
+	// operands are executed with it.
+	setAnnotations(map[string]string{executor.AnnotationForceRerun: "A"})
+	assert.Equal(t, []string{"A", "B"}, reconcile())
+	assert.Empty(t, reconcile())
+
+	setAnnotations(map[string]string{executor.AnnotationForceRerun: "A", executor.AnnotationSkip: "B"})
+	assert.Equal(t, []string{"A"}, reconcile())
+
+	// Other annotations don't affect the skip.
+	setAnnotations(map[string]string{executor.AnnotationForceRerun: "A", executor.AnnotationSkip: "B", "other": "value"})
+	assert.Empty(t, reconcile())
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+// DryRunEnsure runs the ensure of the operands of the object in dry-run
+// mode. The operands receive a context in dry-run mode and must use
+// dryrun.ClientFor to write to the API server, since the operator has no
+// client of its own to wrap with client.DryRunAll. Only the operands that
+// implement operand.DryRunner are executed, the others are reported in the
+// executor.OperandDryRunNotSupported state and the returned error wraps
+// executor.ErrDryRunNotSupported. The ReadyCheck of the operands is skipped.
+// The returned ExecutionReport contains the changes that would have been
+// made. The suspension check is ignored, and neither the status of the
+// object nor the state of the operator is changed.
+func (co *CompositeOperator) DryRunEnsure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (*executor.ExecutionReport, error) {
+	ctx, span, _ := co.inst.Start(ctx, "DryRunEnsure")
+	defer span.End()
+
+	ctx = dryrun.WithRecorder(ctx, dryrun.NewRecorder())
+	_, report, err := co.ensure(ctx, obj, ownerRef)
+	return report, err
+}
+
+// DryRunCleanup runs the cleanup of the operands of the object in dry-run
+// mode, like DryRunEnsure.
+func (co *CompositeOperator) DryRunCleanup(ctx context.Context, obj client.Object) (*executor.ExecutionReport, error) {
+	ctx, span, _ := co.inst.Start(ctx, "DryRunCleanup")
+	defer span.End()
+
+	ctx = dryrun.WithRecorder(ctx, dryrun.NewRecorder())
+	_, report, _, err := co.cleanup(ctx, obj)
+	return report, err
+}
@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// dryRunServer emulates the dry-run support of the API server, which the
+// fake client doesn't have. The dry-run writes are not persisted.
+type dryRunServer struct {
+	client.Client
+}
+
+func (s *dryRunServer) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	o := &client.CreateOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) > 0 {
+		return nil
+	}
+	return s.Client.Create(ctx, obj, opts...)
+}
+
+func (s *dryRunServer) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	o := &client.DeleteOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) > 0 {
+		return s.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj.DeepCopyObject().(client.Object))
+	}
+	return s.Client.Delete(ctx, obj, opts...)
+}
+
+func TestCompositeOperatorDryRun(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+	c := &dryRunServer{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()}
+
+	configMap := func(name string) operand.ResourceBuilder[*corev1.ConfigMap] {
+		return func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+			return &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: parent.GetNamespace()},
+				Data:       map[string]string{"name": name},
+			}, nil
+		}
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	co, err := NewCompositeOperator(
+		WithEventRecorder(recorder),
+		WithOperands(
+			operand.NewResourceOperand("cm-a", c, configMap("a")),
+			operand.NewResourceOperand("cm-b", c, configMap("b"), operand.WithResourceRequires("cm-a")),
+		),
+	)
+	assert.Nil(t, err)
+
+	// Ensure creates both config maps. The readiness check is skipped since
+	// the config maps aren't created.
+	report, err := co.DryRunEnsure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	assert.Equal(t, executor.OperandSucceeded, report.Get("cm-a").State)
+	assert.Equal(t, executor.OperandSucceeded, report.Get("cm-b").State)
+
+	changes := report.Changes()
+	assert.Len(t, changes, 2)
+	assert.Equal(t, dryrun.ActionCreate, changes[0].Action)
+	assert.Equal(t, "a", changes[0].Name)
+	assert.Equal(t, "default", changes[0].Namespace)
+	assert.NotEmpty(t, changes[0].Diff)
+	assert.Equal(t, "b", changes[1].Name)
+	assert.Len(t, report.Get("cm-a").Changes, 1)
+
+	cms := &corev1.ConfigMapList{}
+	assert.Nil(t, c.List(context.Background(), cms))
+	assert.Empty(t, cms.Items)
+
+	// Cleanup deletes the existing config map only.
+	assert.Nil(t, c.Create(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+	}))
+	report, err = co.DryRunCleanup(context.Background(), parent)
+	assert.Nil(t, err)
+	changes = report.Changes()
+	assert.Len(t, changes, 1)
+	assert.Equal(t, dryrun.ActionDelete, changes[0].Action)
+	assert.Equal(t, "a", changes[0].Name)
+
+	assert.Nil(t, c.List(context.Background(), cms))
+	assert.Len(t, cms.Items, 1)
+
+	// No event is recorded in dry-run mode.
+	assert.Empty(t, recorder.Events)
+}
+
+func TestCompositeOperatorDryRunNotSupported(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+	c := &dryRunServer{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()}
+	configMap := func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: parent.GetNamespace()},
+		}, nil
+	}
+
+	// The fake operand doesn't implement operand.DryRunner.
+	rec := &callRecorder{}
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(
+			operand.NewResourceOperand("cm-a", c, configMap),
+			&fakeOperand{name: "other", requires: []string{"cm-a"}, rec: rec},
+		),
+	)
+	assert.Nil(t, err)
+
+	report, err := co.DryRunEnsure(context.Background(), parent, metav1.OwnerReference{})
+	assert.True(t, errors.Is(err, executor.ErrDryRunNotSupported), "expected dry-run error, got: %v", err)
+	assert.Contains(t, err.Error(), "other")
+	assert.Equal(t, executor.OperandSucceeded, report.Get("cm-a").State)
+	assert.Equal(t, executor.OperandDryRunNotSupported, report.Get("other").State)
+	assert.Len(t, report.Changes(), 1)
+
+	report, err = co.DryRunCleanup(context.Background(), parent)
+	assert.True(t, errors.Is(err, executor.ErrDryRunNotSupported), "expected dry-run error, got: %v", err)
+	assert.Equal(t, executor.OperandDryRunNotSupported, report.Get("other").State)
+
+	// The unsupported operand is never executed.
+	ensured, deleted := rec.reset()
+	assert.Empty(t, ensured)
+	assert.Empty(t, deleted)
+}
@@ -0,0 +1,221 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook/order"
+)
+
+// OperandsFunc generates the operands of a parent object at reconcile time,
+// usually from its spec, e.g. one operand per entry in a list.
+type OperandsFunc func(context.Context, client.Object) ([]operand.Operand, error)
+
+// operandSet is the set of operands of a parent object with their
+// requirements and playbooks.
+type operandSet struct {
+	// key identifies the operands and their dependencies. It's empty for the
+	// static operands.
+	key             string
+	operands        []operand.Operand
+	ensureRequired  order.RequiredOperands
+	cleanupRequired order.RequiredOperands
+	ensurePlaybook  *playbook.Playbook
+	cleanupPlaybook *playbook.Playbook
+}
+
+// names returns the names of the operands in the set.
+func (s *operandSet) names() map[string]bool {
+	names := make(map[string]bool, len(s.operands))
+	for _, op := range s.operands {
+		names[op.Name()] = true
+	}
+	return names
+}
+
+// staticOperandSet returns the operandSet of the static operands.
+func (co *CompositeOperator) staticOperandSet() *operandSet {
+	return &operandSet{
+		operands:        co.Operands,
+		ensureRequired:  co.ensureRequired,
+		cleanupRequired: co.cleanupRequired,
+		ensurePlaybook:  co.ensurePlaybook,
+		cleanupPlaybook: co.cleanupPlaybook,
+	}
+}
+
+// operandSetFor returns the operandSet of the given object. Without an
+// OperandsFunc, it's the set of the static operands. Otherwise, the generated
+// operands are added to the static operands. The playbooks are cached by a
+// hash of the operand names and dependencies and are reused with the new
+// operand instances.
+func (co *CompositeOperator) operandSetFor(ctx context.Context, obj client.Object) (*operandSet, error) {
+	if co.operandsFunc == nil {
+		return co.staticOperandSet(), nil
+	}
+
+	generated, err := co.operandsFunc(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate operands: %w", err)
+	}
+
+	set := &operandSet{
+		operands:        append(append([]operand.Operand{}, co.Operands...), generated...),
+		ensureRequired:  order.RequiredOperands{},
+		cleanupRequired: order.RequiredOperands{},
+	}
+	for name, req := range co.ensureRequired {
+		set.ensureRequired[name] = req
+	}
+	for name, req := range co.cleanupRequired {
+		set.cleanupRequired[name] = req
+	}
+	for _, op := range generated {
+		if _, exists := set.ensureRequired[op.Name()]; exists {
+			return nil, fmt.Errorf("duplicate operand name %q", op.Name())
+		}
+		set.ensureRequired[op.Name()] = op.Requires()
+		set.cleanupRequired[op.Name()] = op.CleanupRequires()
+	}
+	set.key = operandSetKey(set.ensureRequired, set.cleanupRequired)
+
+	var pbs playbookPair
+	if v, ok := co.playbookCache.Get(set.key); ok {
+		pbs = v.(playbookPair)
+	} else {
+		pbs.ensure, err = playbook.NewPlaybookFromRequired(set.operands, set.ensureRequired, operand.Ensure)
+		if err != nil {
+			return nil, err
+		}
+		pbs.cleanup, err = playbook.NewPlaybookFromRequired(set.operands, set.cleanupRequired, operand.Cleanup)
+		if err != nil {
+			return nil, err
+		}
+		co.playbookCache.Add(set.key, pbs)
+	}
+	set.ensurePlaybook = pbs.ensure.WithOperands(set.operands)
+	set.cleanupPlaybook = pbs.cleanup.WithOperands(set.operands)
+
+	return set, nil
+}
+
+// operandSetKey returns a hash of the operand names and their ensure and
+// cleanup dependencies.
+func operandSetKey(ensureRequired, cleanupRequired order.RequiredOperands) string {
+	names := make([]string, 0, len(ensureRequired))
+	for name := range ensureRequired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		ensure := append([]string{}, ensureRequired[name]...)
+		cleanup := append([]string{}, cleanupRequired[name]...)
+		sort.Strings(ensure)
+		sort.Strings(cleanup)
+		fmt.Fprintf(h, "%s:%s:%s\n", name, strings.Join(ensure, ","), strings.Join(cleanup, ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cleanupRemovedOperands cleans up the operands that were part of the
+// operand set of the object in a previous reconciliation but aren't part of
+// the given set anymore. The operands of the given set are remembered for the
+// next reconciliation. The removed operands that fail to clean up are retried
+// in the next reconciliation. The previous operands are kept in memory and
+// are lost on restart.
+func (co *CompositeOperator) cleanupRemovedOperands(ctx context.Context, obj client.Object, set *operandSet) (ctrl.Result, *executor.ExecutionReport, error) {
+	ctx, span, _ := co.inst.Start(ctx, "cleanupRemovedOperands")
+	defer span.End()
+
+	report := &executor.ExecutionReport{}
+	current := set.names()
+
+	removed := []operand.Operand{}
+	if v, ok := co.previousOperands.Load(obj.GetUID()); ok {
+		for name, op := range v.(map[string]operand.Operand) {
+			if !current[name] {
+				removed = append(removed, op)
+			}
+		}
+	}
+
+	next := make(map[string]operand.Operand, len(set.operands))
+	for _, op := range set.operands {
+		next[op.Name()] = op
+	}
+
+	if len(removed) == 0 {
+		co.storePreviousOperands(ctx, obj, next)
+		return ctrl.Result{}, report, nil
+	}
+
+	span.AddEvent("Cleaning up removed operands")
+
+	// Clean up the removed operands in their cleanup order.
+	cleanupRequired, err := order.Required(removed, operand.Cleanup)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, report, err
+	}
+	cleanupPB, err := playbook.NewPlaybookFromRequired(removed, removedRequired(cleanupRequired), operand.Cleanup)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, report, err
+	}
+
+	result, report, err := co.executor.ExecuteOperands(cleanupPB.Order(), cleanupPB.Blockers(), operand.CallCleanup, operand.Cleanup, ctx, obj, metav1.OwnerReference{})
+	for i := range report.Operands {
+		r := &report.Operands[i]
+		if r.State == executor.OperandSucceeded {
+			r.State = executor.OperandRemoved
+			r.Ready = false
+			continue
+		}
+		// Retry the cleanup of the failed operands in the next
+		// reconciliation.
+		for _, op := range removed {
+			if op.Name() == r.Name {
+				next[op.Name()] = op
+			}
+		}
+	}
+	co.storePreviousOperands(ctx, obj, next)
+
+	return result, report, err
+}
+
+// storePreviousOperands stores the operands of the object for the next
+// reconciliation. Nothing is stored in dry-run mode.
+func (co *CompositeOperator) storePreviousOperands(ctx context.Context, obj client.Object, operands map[string]operand.Operand) {
+	if dryrun.IsDryRun(ctx) {
+		return
+	}
+	co.previousOperands.Store(obj.GetUID(), operands)
+}
+
+// removedRequired drops the requirements on the operands that aren't part of
+// the given RequiredOperands, like the operands that are still part of the
+// operand set of the object.
+func removedRequired(required order.RequiredOperands) order.RequiredOperands {
+	unknown := map[string]bool{}
+	for _, reqs := range required {
+		for _, req := range reqs {
+			if _, ok := required[req]; !ok {
+				unknown[req] = true
+			}
+		}
+	}
+	return required.Without(unknown)
+}
@@ -0,0 +1,183 @@
+package v1
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// callRecorder records the operand calls.
+type callRecorder struct {
+	mu      sync.Mutex
+	ensured []string
+	deleted []string
+}
+
+func (r *callRecorder) reset() ([]string, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ensured, deleted := r.ensured, r.deleted
+	sort.Strings(ensured)
+	sort.Strings(deleted)
+	r.ensured, r.deleted = nil, nil
+	return ensured, deleted
+}
+
+// fakeOperand is an operand that records its calls.
+type fakeOperand struct {
+	name            string
+	requires        []string
+	cleanupRequires []string
+	rec             *callRecorder
+}
+
+var _ operand.Operand = &fakeOperand{}
+
+func (o *fakeOperand) Name() string                             { return o.name }
+func (o *fakeOperand) Requires() []string                       { return o.requires }
+func (o *fakeOperand) CleanupRequires() []string                { return o.cleanupRequires }
+func (o *fakeOperand) RequeueStrategy() operand.RequeueStrategy { return operand.RequeueOnError }
+func (o *fakeOperand) ReadyCheck(context.Context, client.Object) (bool, error) {
+	return true, nil
+}
+func (o *fakeOperand) PostReady(context.Context, client.Object) error { return nil }
+
+func (o *fakeOperand) Ensure(context.Context, client.Object, metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	o.rec.mu.Lock()
+	defer o.rec.mu.Unlock()
+	o.rec.ensured = append(o.rec.ensured, o.name)
+	return nil, nil
+}
+
+func (o *fakeOperand) Delete(context.Context, client.Object) (eventv1.ReconcilerEvent, error) {
+	o.rec.mu.Lock()
+	defer o.rec.mu.Unlock()
+	o.rec.deleted = append(o.rec.deleted, o.name)
+	return nil, nil
+}
+
+func TestCompositeOperatorOperandsFunc(t *testing.T) {
+	rec := &callRecorder{}
+
+	// One operand per pool in the pools annotation of the parent, requiring
+	// the static base operand.
+	poolOperands := func(ctx context.Context, obj client.Object) ([]operand.Operand, error) {
+		ops := []operand.Operand{}
+		for _, pool := range strings.Split(obj.GetAnnotations()["pools"], ",") {
+			if pool == "" {
+				continue
+			}
+			ops = append(ops, &fakeOperand{name: "pool-" + pool, requires: []string{"base"}, rec: rec})
+		}
+		return ops, nil
+	}
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(&fakeOperand{name: "base", rec: rec}),
+		WithOperandsFunc(poolOperands),
+	)
+	assert.Nil(t, err)
+
+	parent := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "parent-uid"}}
+	setPools := func(pools string) {
+		parent.SetAnnotations(map[string]string{"pools": pools})
+	}
+
+	// Ensure with two pools.
+	setPools("a,b")
+	_, report, err := co.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	ensured, deleted := rec.reset()
+	assert.Equal(t, []string{"base", "pool-a", "pool-b"}, ensured)
+	assert.Empty(t, deleted)
+	assert.Equal(t, 0, report.Get("base").Step)
+	assert.Equal(t, 1, report.Get("pool-a").Step)
+
+	// The playbook is reused for the same operand set.
+	_, _, err = co.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	rec.reset()
+	assert.Equal(t, 1, co.playbookCache.Len())
+
+	// Remove a pool, its operand is cleaned up.
+	setPools("a")
+	_, report, err = co.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	ensured, deleted = rec.reset()
+	assert.Equal(t, []string{"base", "pool-a"}, ensured)
+	assert.Equal(t, []string{"pool-b"}, deleted)
+	assert.Equal(t, executor.OperandRemoved, report.Get("pool-b").State)
+
+	// The removed operand is cleaned up only once.
+	_, _, err = co.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	_, deleted = rec.reset()
+	assert.Empty(t, deleted)
+
+	// Cleanup cleans up the current operands.
+	_, _, err = co.Cleanup(context.Background(), parent)
+	assert.Nil(t, err)
+	_, deleted = rec.reset()
+	assert.Equal(t, []string{"base", "pool-a"}, deleted)
+	_, found := co.previousOperands.Load(parent.GetUID())
+	assert.False(t, found)
+}
+
+func TestCompositeOperatorOperandsFuncForget(t *testing.T) {
+	rec := &callRecorder{}
+	poolOperands := func(ctx context.Context, obj client.Object) ([]operand.Operand, error) {
+		return []operand.Operand{&fakeOperand{name: "pool-" + obj.GetName(), rec: rec}}, nil
+	}
+
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperandsFunc(poolOperands),
+		WithPlaybookCacheSize(1),
+	)
+	assert.Nil(t, err)
+
+	podA := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", UID: "uid-a"}}
+	podB := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", UID: "uid-b"}}
+	for _, pod := range []*corev1.Pod{podA, podB} {
+		_, _, err = co.Ensure(context.Background(), pod, metav1.OwnerReference{})
+		assert.Nil(t, err)
+	}
+
+	// Only the playbook of the last operand set is kept.
+	assert.Equal(t, 1, co.playbookCache.Len())
+
+	// The previous operands are forgotten once the object is deleted.
+	co.Forget(context.Background(), podA)
+	_, found := co.previousOperands.Load(podA.GetUID())
+	assert.False(t, found)
+	_, found = co.previousOperands.Load(podB.GetUID())
+	assert.True(t, found)
+}
+
+func TestCompositeOperatorOperandsFuncDuplicate(t *testing.T) {
+	rec := &callRecorder{}
+	co, err := NewCompositeOperator(
+		WithEventRecorder(record.NewFakeRecorder(10)),
+		WithOperands(&fakeOperand{name: "base", rec: rec}),
+		WithOperandsFunc(func(ctx context.Context, obj client.Object) ([]operand.Operand, error) {
+			return []operand.Operand{&fakeOperand{name: "base", rec: rec}}, nil
+		}),
+	)
+	assert.Nil(t, err)
+
+	_, _, err = co.Ensure(context.Background(), &corev1.Pod{}, metav1.OwnerReference{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/constant"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// AnnotationPrefix is the prefix of the annotations of a parent object that
+// control the execution of its operands.
+const AnnotationPrefix = constant.AnnotationPrefix
+
+const (
+	// AnnotationPauseAfterStep pauses the execution after the given step
+	// index. The operands in the following steps are not executed and are
+	// reported as paused. Incrementing the value runs one more step at a
+	// time.
+	AnnotationPauseAfterStep = AnnotationPrefix + "pause-after-step"
+	// AnnotationRunOnly is a comma separated list of the only operands to
+	// execute. The other operands are skipped.
+	AnnotationRunOnly = AnnotationPrefix + "run-only"
+	// AnnotationSkip is a comma separated list of operands to skip.
+	AnnotationSkip = AnnotationPrefix + "skip"
+	// AnnotationForceRerun is a comma separated list of operands to run
+	// even if they are skipped by AnnotationRunOnly or AnnotationSkip. A
+	// change in the value also resets the backoff of the operands.
+	AnnotationForceRerun = AnnotationPrefix + "force-rerun"
+)
+
+// Reasons of the events recorded on the parent object when the execution is
+// changed by the control annotations.
+const (
+	ReasonExecutionPaused   = "ExecutionPaused"
+	ReasonOperandsSkipped   = "OperandsSkipped"
+	ReasonOperandsForced    = "OperandsForced"
+	ReasonInvalidAnnotation = "InvalidControlAnnotation"
+)
+
+// Control is the execution control of a parent object, set through the
+// control annotations.
+type Control struct {
+	// PauseAfterStep is the index of the step after which the execution is
+	// paused. A negative value means no pause.
+	PauseAfterStep int
+	// RunOnly are the only operands to execute, if not empty.
+	RunOnly map[string]bool
+	// Skip are the operands to skip.
+	Skip map[string]bool
+	// ForceRerun are the operands to run regardless of RunOnly and Skip.
+	ForceRerun map[string]bool
+}
+
+// ParseControl returns the execution control of the given object from its
+// annotations. The invalid annotations are ignored and returned as an error
+// along with the control of the valid annotations.
+func ParseControl(obj client.Object) (*Control, error) {
+	c := &Control{PauseAfterStep: -1}
+	annotations := obj.GetAnnotations()
+
+	var errs []error
+	if v, ok := annotations[AnnotationPauseAfterStep]; ok {
+		step, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || step < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: must be a non-negative step index", AnnotationPauseAfterStep, v))
+		} else {
+			c.PauseAfterStep = step
+		}
+	}
+	c.RunOnly = parseNames(annotations[AnnotationRunOnly])
+	c.Skip = parseNames(annotations[AnnotationSkip])
+	c.ForceRerun = parseNames(annotations[AnnotationForceRerun])
+
+	return c, kerrors.NewAggregate(errs)
+}
+
+// parseNames parses a comma separated list of operand names.
+func parseNames(value string) map[string]bool {
+	names := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// IsZero tells if the control doesn't change the execution.
+func (c *Control) IsZero() bool {
+	return c == nil || (c.PauseAfterStep < 0 && len(c.RunOnly) == 0 && len(c.Skip) == 0 && len(c.ForceRerun) == 0)
+}
+
+// pausedBefore tells if the execution is paused before the given step.
+func (c *Control) pausedBefore(step int) bool {
+	return c != nil && c.PauseAfterStep >= 0 && step > c.PauseAfterStep
+}
+
+// forced tells if the given operand is forced to run.
+func (c *Control) forced(name string) bool {
+	return c != nil && c.ForceRerun[name]
+}
+
+// skipped tells if the given operand is skipped.
+func (c *Control) skipped(name string) bool {
+	if c == nil || c.forced(name) {
+		return false
+	}
+	if len(c.RunOnly) > 0 && !c.RunOnly[name] {
+		return true
+	}
+	return c.Skip[name]
+}
+
+// filter returns the operands of a step to execute and the reports of the
+// skipped operands.
+func (c *Control) filter(step int, ops []operand.Operand) ([]operand.Operand, []OperandReport) {
+	if c.IsZero() {
+		return ops, nil
+	}
+	run := make([]operand.Operand, 0, len(ops))
+	skipped := []OperandReport{}
+	for _, op := range ops {
+		if c.skipped(op.Name()) {
+			skipped = append(skipped, OperandReport{Name: op.Name(), Step: step, State: OperandSkipped})
+			continue
+		}
+		run = append(run, op)
+	}
+	return run, skipped
+}
+
+// sortedNames returns the sorted names of a set of operand names.
+func sortedNames(names map[string]bool) []string {
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseControl(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *Control
+		wantErr     bool
+	}{
+		{
+			name: "no annotations",
+			want: &Control{PauseAfterStep: -1, RunOnly: map[string]bool{}, Skip: map[string]bool{}, ForceRerun: map[string]bool{}},
+		},
+		{
+			name: "all annotations",
+			annotations: map[string]string{
+				AnnotationPauseAfterStep: "2",
+				AnnotationRunOnly:        "a, b,",
+				AnnotationSkip:           "c",
+				AnnotationForceRerun:     " d ",
+			},
+			want: &Control{
+				PauseAfterStep: 2,
+				RunOnly:        map[string]bool{"a": true, "b": true},
+				Skip:           map[string]bool{"c": true},
+				ForceRerun:     map[string]bool{"d": true},
+			},
+		},
+		{
+			name: "invalid pause step",
+			annotations: map[string]string{
+				AnnotationPauseAfterStep: "-1",
+				AnnotationSkip:           "c",
+			},
+			want:    &Control{PauseAfterStep: -1, RunOnly: map[string]bool{}, Skip: map[string]bool{"c": true}, ForceRerun: map[string]bool{}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			got, err := ParseControl(obj)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestControlSkipped(t *testing.T) {
+	c := &Control{
+		PauseAfterStep: -1,
+		RunOnly:        map[string]bool{"a": true},
+		Skip:           map[string]bool{"a": true, "b": true},
+		ForceRerun:     map[string]bool{"b": true},
+	}
+	// a is in run-only but also skipped.
+	assert.True(t, c.skipped("a"))
+	// b is forced.
+	assert.False(t, c.skipped("b"))
+	// c is not in run-only.
+	assert.True(t, c.skipped("c"))
+
+	var nilControl *Control
+	assert.False(t, nilControl.skipped("a"))
+	assert.True(t, nilControl.IsZero())
+}
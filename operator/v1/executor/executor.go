@@ -2,8 +2,12 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -13,11 +17,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/ondat/operator-toolkit/client/dryrun"
 	"github.com/ondat/operator-toolkit/constant"
+	tkctrl "github.com/ondat/operator-toolkit/controller"
 	eventv1 "github.com/ondat/operator-toolkit/event/v1"
 	"github.com/ondat/operator-toolkit/operator/v1/operand"
 	"github.com/ondat/operator-toolkit/operator/v1/playbook/order"
 	"github.com/ondat/operator-toolkit/telemetry"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
 )
 
 // Name of the instrumentation.
@@ -28,9 +35,39 @@ type ExecutionStrategy int
 
 const (
 	Parallel ExecutionStrategy = iota
+	// Serial runs the operands in a step one after the other. The execution
+	// of the step stops at the first failed operand and the operands after
+	// it are reported as blocked. Since they didn't run, a blocking operand
+	// among them blocks the following steps like a failed blocking operand,
+	// so that no operand runs before the operands it requires.
 	Serial
+	// BoundedParallel runs the operands in a step concurrently with at most
+	// a configured number of operands running at the same time. When a
+	// blocking operand fails, the running operands in the step are cancelled
+	// and the operands that haven't started are not executed.
+	BoundedParallel
 )
 
+// ReasonOperandPanic is the reason of the Warning event recorded on the parent
+// object when an operand panics.
+const ReasonOperandPanic = "OperandPanic"
+
+// ErrCleanupNotExecuted is the error of a cleanup with operands that weren't
+// executed because they're paused or skipped by the control annotations.
+var ErrCleanupNotExecuted = errors.New("operands not cleaned up because of the control annotations")
+
+// ErrDryRunNotSupported is the error of a dry-run execution with operands that
+// weren't executed because they don't support the dry-run mode.
+var ErrDryRunNotSupported = errors.New("operands not executed because they don't support dry-run")
+
+// defaultMaxConcurrency is the default maximum number of operands executed
+// concurrently with the BoundedParallel execution strategy.
+const defaultMaxConcurrency = 5
+
+// defaultMaxOperandLabels is the default maximum number of operand names used
+// as the operand label of the operand metrics.
+const defaultMaxOperandLabels = 100
+
 // Executor is an operand executor. It is used to configure how the operands
 // are executed. The event recorder is used to broadcast an event right after
 // executing an operand.
@@ -38,54 +75,170 @@ type Executor struct {
 	execStrategy ExecutionStrategy
 	recorder     record.EventRecorder
 
+	// name is the name of the reconciler of the operands, used in the
+	// operand metrics.
+	name string
+
+	// operandTimeout is the default timeout of the operands that don't
+	// implement operand.Timeouter.
+	operandTimeout time.Duration
+
+	// maxConcurrency is the maximum number of operands executed concurrently
+	// with the BoundedParallel execution strategy.
+	maxConcurrency int
+
+	// maxOperandLabels is the maximum number of operand names used as the
+	// operand label of the operand metrics, see operandLabel.
+	maxOperandLabels int
+	// operandLabels are the operand names used as the operand label of the
+	// operand metrics.
+	operandLabels   map[string]bool
+	operandLabelsMu sync.Mutex
+
+	// inFlight stores the running operand calls with a timeout, by UID of
+	// the parent object and operand name, to not run an operand again while
+	// its call that timed out is still running.
+	inFlight sync.Map
+
 	inst *telemetry.Instrumentation
 }
 
+// ExecutorOption is used to configure Executor.
+type ExecutorOption func(*Executor)
+
+// WithName sets the name of the reconciler of the operands, used as the
+// reconciler label of the operand metrics.
+func WithName(name string) ExecutorOption {
+	return func(e *Executor) {
+		e.name = name
+	}
+}
+
+// WithOperandTimeout sets the default timeout of an operand execution. It's
+// used for the operands that don't implement operand.Timeouter. A zero
+// timeout means no timeout.
+func WithOperandTimeout(timeout time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.operandTimeout = timeout
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of operands executed
+// concurrently with the BoundedParallel execution strategy.
+func WithMaxConcurrency(n int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxConcurrency = n
+	}
+}
+
+// WithMaxOperandLabels sets the maximum number of operand names used as the
+// operand label of the operand metrics. The metrics of the operands executed
+// once the limit is reached are recorded with the metrics.OperandOther
+// operand label. It bounds the number of metric series when the operands are
+// generated per parent object, with names that aren't known in advance.
+func WithMaxOperandLabels(n int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxOperandLabels = n
+	}
+}
+
 // NewExecutor initializes and returns an Executor.
-func NewExecutor(e ExecutionStrategy, r record.EventRecorder) *Executor {
-	return &Executor{
-		execStrategy: e,
-		recorder:     r,
-		inst:         telemetry.NewInstrumentation(instrumentationName),
+func NewExecutor(e ExecutionStrategy, r record.EventRecorder, opts ...ExecutorOption) *Executor {
+	exe := &Executor{
+		execStrategy:  e,
+		recorder:      r,
+		operandLabels: map[string]bool{},
+		inst:          telemetry.NewInstrumentation(instrumentationName),
 	}
+
+	for _, opt := range opts {
+		opt(exe)
+	}
+
+	if exe.maxConcurrency <= 0 {
+		exe.maxConcurrency = defaultMaxConcurrency
+	}
+	if exe.maxOperandLabels <= 0 {
+		exe.maxOperandLabels = defaultMaxOperandLabels
+	}
+
+	return exe
 }
 
 // ExecuteOperands executes operands in a given OperandOrder by calling a given
 // OperandRunCall function on each of the operands. The OperandRunCall can be a
-// call to Ensure or Delete.
+// call to Ensure or Delete, the OperandRunCallName tells which one it is. It
+// returns an ExecutionReport with the state of each of the operands after the
+// execution. The execution can be changed through the control annotations of
+// the object, see ParseControl. A cleanup with operands paused or skipped by
+// the control annotations fails with ErrCleanupNotExecuted, to keep the
+// finalizer of the object until all the operands are cleaned up. In dry-run
+// mode, the operands that don't implement operand.DryRunner are not executed
+// and the execution fails with ErrDryRunNotSupported.
 func (exe *Executor) ExecuteOperands(
 	operandOrder order.OperandOrder,
 	blockers order.BlockingOperands,
 	call operand.OperandRunCall,
+	callName operand.OperandRunCallName,
 	ctx context.Context,
 	obj client.Object,
 	ownerRef metav1.OwnerReference,
-) (result ctrl.Result, rerr error) {
+) (result ctrl.Result, report *ExecutionReport, rerr error) {
 	ctx, span, _ := exe.inst.Start(ctx, "execute")
 	defer span.End()
 
+	report = &ExecutionReport{}
+
+	// Invalid control annotations are ignored. No event is recorded in
+	// dry-run mode.
+	dryRun := dryrun.IsDryRun(ctx)
+	control, err := ParseControl(obj)
+	if err != nil {
+		span.RecordError(err)
+		if !dryRun {
+			exe.recorder.Event(obj, eventv1.K8sEventTypeWarning, ReasonInvalidAnnotation, err.Error())
+		}
+	}
+	if !dryRun {
+		defer exe.recordControlEvents(obj, control, report)
+	}
+
 	span.SetAttributes(attribute.Int("order-length", len(operandOrder)))
 	span.AddEvent("Start operand execution")
 	// Iterate through the order steps and run the operands in the steps as per
 	// the execution strategy.
-	for _, ops := range operandOrder {
+	for step, ops := range operandOrder {
+		// Stop the execution if it's paused before this step.
+		if control.pausedBefore(step) {
+			span.AddEvent("Execution paused", trace.WithAttributes(attribute.Int("step", step)))
+			report.add(unexecutedReports(operandOrder, step, OperandPaused)...)
+			break
+		}
+
+		// Skip the operands excluded by the control annotations.
+		ops, skipped := control.filter(step, ops)
+		report.add(skipped...)
+
+		// Never execute the operands that would apply their changes in
+		// dry-run mode.
+		if dryRun {
+			var unsupported []OperandReport
+			ops, unsupported = dryRunFilter(step, ops)
+			report.add(unsupported...)
+		}
+
 		// Error in the current execution step.
 		var execErr error
 
-		// res is the Result of the step.
-		// TODO: Change the type of res to something that reflects that a
-		// change took place. The value of Result is not propagated to the
-		// caller.
-		var res *ctrl.Result
-
-		// failedOperands are the operands that have failed during this step.
-		var failedOperands map[string]bool
+		// stepReports are the reports of the operands in this step.
+		var stepReports []OperandReport
 
 		requeueStrategy := order.StepRequeueStrategy(ops)
 
 		span.AddEvent(
 			"Execute operands",
 			trace.WithAttributes(
+				attribute.Int("step", step),
 				attribute.Int("requeue-strategy", int(requeueStrategy)),
 			),
 		)
@@ -93,14 +246,19 @@ func (exe *Executor) ExecuteOperands(
 		switch exe.execStrategy {
 		case Serial:
 			// Run the operands serially.
-			res, failedOperands, execErr = exe.serialExec(ops, call, ctx, obj, ownerRef)
+			stepReports, execErr = exe.serialExec(step, ops, call, ctx, obj, ownerRef)
 		case Parallel:
 			// Run the operands concurrently.
-			res, failedOperands, execErr = exe.concurrentExec(ops, call, ctx, obj, ownerRef)
+			stepReports, execErr = exe.concurrentExec(step, ops, call, ctx, obj, ownerRef)
+		case BoundedParallel:
+			// Run the operands concurrently with a limit.
+			stepReports, execErr = exe.boundedConcurrentExec(step, ops, blockers, call, ctx, obj, ownerRef)
 		default:
 			rerr = fmt.Errorf("unknown operands execution strategy: %v", exe.execStrategy)
 			return
 		}
+		report.add(stepReports...)
+
 		if execErr != nil {
 			rerr = kerrors.NewAggregate([]error{rerr, execErr})
 			// Check if any failed operands are also blocking operands.
@@ -108,7 +266,8 @@ func (exe *Executor) ExecuteOperands(
 			// Otherwise, continue to the next step in the order.
 			// In either case, the result is to requeue.
 			result = ctrl.Result{Requeue: true}
-			if failuresContainBlockingOperand(failedOperands, blockers) {
+			if failuresContainBlockingOperand(failedOperands(stepReports), blockers) {
+				report.add(unexecutedReports(operandOrder, step+1, OperandBlocked)...)
 				break
 			}
 			continue
@@ -116,17 +275,114 @@ func (exe *Executor) ExecuteOperands(
 
 		// If a change was made with a Result received after the execution and
 		// the RequeueStrategy is RequeueAlways, set a requeued result.
-		if res != nil && requeueStrategy == operand.RequeueAlways {
+		if changeApplied(stepReports) && requeueStrategy == operand.RequeueAlways {
 			result = ctrl.Result{Requeue: true}
+			report.add(unexecutedReports(operandOrder, step+1, OperandPending)...)
 			break
 		}
 	}
 
+	// The operands that weren't cleaned up because of the control
+	// annotations would be left behind once the cleanup succeeds.
+	if callName == operand.Cleanup {
+		notExecuted := append(report.InState(OperandPaused), report.InState(OperandSkipped)...)
+		if len(notExecuted) > 0 {
+			result = ctrl.Result{Requeue: true}
+			rerr = kerrors.NewAggregate([]error{rerr, fmt.Errorf("%w: %s", ErrCleanupNotExecuted, strings.Join(notExecuted, ", "))})
+		}
+	}
+
+	if unsupported := report.InState(OperandDryRunNotSupported); len(unsupported) > 0 {
+		rerr = kerrors.NewAggregate([]error{rerr, fmt.Errorf("%w: %s", ErrDryRunNotSupported, strings.Join(unsupported, ", "))})
+	}
+
 	span.AddEvent("Finish operand execution")
 
 	return
 }
 
+// dryRunFilter returns the operands of a step that support the dry-run mode
+// and the reports of the other operands.
+func dryRunFilter(step int, ops []operand.Operand) ([]operand.Operand, []OperandReport) {
+	run := make([]operand.Operand, 0, len(ops))
+	unsupported := []OperandReport{}
+	for _, op := range ops {
+		if dr, ok := op.(operand.DryRunner); ok && dr.SupportsDryRun() {
+			run = append(run, op)
+			continue
+		}
+		unsupported = append(unsupported, OperandReport{
+			Name:  op.Name(),
+			Step:  step,
+			State: OperandDryRunNotSupported,
+			Error: fmt.Errorf("operand %q: %w", op.Name(), ErrDryRunNotSupported),
+		})
+	}
+	return run, unsupported
+}
+
+// recordControlEvents records events on the object about the operands that
+// were skipped, paused or forced by the control annotations.
+func (exe *Executor) recordControlEvents(obj client.Object, control *Control, report *ExecutionReport) {
+	if control.IsZero() {
+		return
+	}
+	if paused := report.InState(OperandPaused); len(paused) > 0 {
+		exe.recorder.Eventf(obj, eventv1.K8sEventTypeNormal, ReasonExecutionPaused,
+			"Execution paused after step %d, operands not executed: %s", control.PauseAfterStep, strings.Join(paused, ", "))
+	}
+	if skipped := report.InState(OperandSkipped); len(skipped) > 0 {
+		exe.recorder.Eventf(obj, eventv1.K8sEventTypeNormal, ReasonOperandsSkipped,
+			"Skipped operands: %s", strings.Join(skipped, ", "))
+	}
+	forced := []string{}
+	for _, name := range sortedNames(control.ForceRerun) {
+		if r := report.Get(name); r != nil && r.State != OperandPaused && r.State != OperandPending && r.State != OperandBlocked {
+			forced = append(forced, name)
+		}
+	}
+	if len(forced) > 0 {
+		exe.recorder.Eventf(obj, eventv1.K8sEventTypeNormal, ReasonOperandsForced,
+			"Forced run of operands: %s", strings.Join(forced, ", "))
+	}
+}
+
+// failedOperands returns a map of the operands in the given reports with
+// their failure status. Operands that were not executed successfully,
+// including the ones that were blocked, are considered failed. With the Serial
+// execution strategy, a blocking operand that didn't run after a failed
+// operand of its step then blocks the following steps.
+func failedOperands(reports []OperandReport) map[string]bool {
+	failed := make(map[string]bool, len(reports))
+	for _, r := range reports {
+		failed[r.Name] = r.State != OperandSucceeded
+	}
+	return failed
+}
+
+// changeApplied returns true if any of the operands in the given reports
+// applied a change.
+func changeApplied(reports []OperandReport) bool {
+	for _, r := range reports {
+		if r.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// unexecutedReports returns reports with the given state for all the operands
+// in the OperandOrder starting from the given step.
+func unexecutedReports(operandOrder order.OperandOrder, fromStep int, state OperandState) []OperandReport {
+	reports := []OperandReport{}
+	for step := fromStep; step < len(operandOrder); step++ {
+		for _, op := range operandOrder[step] {
+			reports = append(reports, OperandReport{Name: op.Name(), Step: step, State: state})
+		}
+	}
+	return reports
+}
+
 // failuresContainBlockingOperand returns true if a blocking operand is also a failed operand.
 func failuresContainBlockingOperand(failedOperands map[string]bool, blockers order.BlockingOperands) bool {
 	for operandName, isBlocker := range blockers {
@@ -138,46 +394,42 @@ func failuresContainBlockingOperand(failedOperands map[string]bool, blockers ord
 }
 
 // serialExec runs the given set of operands serially with the given call
-// function. An event is used to know if a change was applied. When an event is
-// found, a result object is returned, else nil.
+// function and returns the reports of the operands. The operands after a
+// failed operand are not executed and are reported as blocked.
 func (exe *Executor) serialExec(
+	step int,
 	ops []operand.Operand,
 	call operand.OperandRunCall,
 	ctx context.Context,
 	obj client.Object,
 	ownerRef metav1.OwnerReference,
-) (result *ctrl.Result, failedOperands map[string]bool, rerr error) {
+) (reports []OperandReport, rerr error) {
 	ctx, span, _ := exe.inst.Start(ctx, "serial-exec")
 	defer span.End()
 
-	result = nil
-
-	failedOperands = make(map[string]bool)
+	reports = make([]OperandReport, 0, len(ops))
 
 	span.AddEvent(
 		"Execute serially",
 		trace.WithAttributes(attribute.Int("operand-count", len(ops))),
 	)
 
-	for _, op := range ops {
+	for i, op := range ops {
 		span.AddEvent(
 			"Executing operand",
 			trace.WithAttributes(attribute.String("operand-name", op.Name())),
 		)
-		// Initially operand as not failed.
-		failedOperands[op.Name()] = false
 		// Call the run call function. Since this is serial execution, return
 		// if an error occurs.
-		event, err := call(op)(ctx, obj, ownerRef)
-		if err != nil {
-			rerr = kerrors.NewAggregate([]error{rerr, err})
-			failedOperands[op.Name()] = true
+		r := exe.runOperand(step, op, call, ctx, obj, ownerRef)
+		reports = append(reports, r)
+		if r.Error != nil {
+			rerr = kerrors.NewAggregate([]error{rerr, r.Error})
+			for _, blocked := range ops[i+1:] {
+				reports = append(reports, OperandReport{Name: blocked.Name(), Step: step, State: OperandBlocked})
+			}
 			return
 		}
-		if event != nil {
-			event.Record(exe.recorder)
-			result = &ctrl.Result{}
-		}
 	}
 
 	span.AddEvent("Finish serial execution")
@@ -185,109 +437,372 @@ func (exe *Executor) serialExec(
 	return
 }
 
-// concurrentExec runs the operands concurrently, collecting the errors from
-// the operand executions and returns them.
+// concurrentExec runs the operands concurrently, collecting the reports and
+// errors from the operand executions and returns them.
 func (exe *Executor) concurrentExec(
+	step int,
 	ops []operand.Operand,
 	call operand.OperandRunCall,
 	ctx context.Context,
 	obj client.Object,
 	ownerRef metav1.OwnerReference,
-) (result *ctrl.Result, failedOperands map[string]bool, rerr error) {
+) (reports []OperandReport, rerr error) {
 	ctx, span, _ := exe.inst.Start(ctx, "concurrent-exec")
 	defer span.End()
 
-	result = nil
-
-	failedOperands = make(map[string]bool)
-
 	// Wait group to synchronize the go routines.
 	var wg sync.WaitGroup
 
-	totalOperands := len(ops)
-
-	// resultChan is used to collect the result returned from the concurrent
-	// execution of the operands.
-	var resultChan chan ctrl.Result = make(chan ctrl.Result, totalOperands)
-
-	// Error buffered channel to collect all the errors from the go routines.
-	var errChan chan error = make(chan error, totalOperands)
-
-	// String buffered channel to collect the names of operands that have failed
-	// to execute.
-	var failedOperandChan chan string = make(chan string, totalOperands)
+	// Each go routine writes the report of its operand at the index of the
+	// operand.
+	reports = make([]OperandReport, len(ops))
 
 	span.AddEvent(
 		"Execute concurrently",
 		trace.WithAttributes(attribute.Int("operand-count", len(ops))),
 	)
 
-	wg.Add(totalOperands)
-	for _, op := range ops {
+	wg.Add(len(ops))
+	for i, op := range ops {
 		span.AddEvent(
 			"Executing operand",
 			trace.WithAttributes(attribute.String("operand-name", op.Name())),
 		)
-		// Initially set operand as not failed.
-		failedOperands[op.Name()] = false
-		go exe.operateWithWaitGroup(&wg, resultChan, failedOperandChan, errChan, call(op), ctx, obj, ownerRef, op.Name())
+		go exe.operateWithWaitGroup(&wg, &reports[i], step, op, call, ctx, obj, ownerRef)
 	}
 	wg.Wait()
-	close(errChan)
-	close(failedOperandChan)
 
 	// Check if any errors were encountered.
-	for err := range errChan {
-		rerr = kerrors.NewAggregate([]error{rerr, err})
+	for _, r := range reports {
+		if r.Error != nil {
+			rerr = kerrors.NewAggregate([]error{rerr, r.Error})
+		}
 	}
 
-	// Set any failed operands to true.
-	for failedOperand := range failedOperandChan {
-		failedOperands[failedOperand] = true
-	}
+	span.AddEvent("Finish concurrent execution")
+
+	return
+}
+
+// boundedConcurrentExec runs the operands concurrently with at most
+// maxConcurrency operands running at the same time, collecting the reports and
+// errors from the operand executions and returns them. If a blocking operand
+// fails, the context of the running operands is cancelled and the operands
+// that haven't started are not executed. The cancelled and unexecuted operands
+// are reported as blocked.
+func (exe *Executor) boundedConcurrentExec(
+	step int,
+	ops []operand.Operand,
+	blockers order.BlockingOperands,
+	call operand.OperandRunCall,
+	ctx context.Context,
+	obj client.Object,
+	ownerRef metav1.OwnerReference,
+) (reports []OperandReport, rerr error) {
+	ctx, span, _ := exe.inst.Start(ctx, "bounded-concurrent-exec")
+	defer span.End()
+
+	// Context used to cancel the sibling operands when a blocking operand
+	// fails.
+	stepCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	// sem limits the number of operands running at the same time.
+	sem := make(chan struct{}, exe.maxConcurrency)
+
+	// Each go routine writes the report of its operand at the index of the
+	// operand.
+	reports = make([]OperandReport, len(ops))
+
+	span.AddEvent(
+		"Execute concurrently with limit",
+		trace.WithAttributes(
+			attribute.Int("operand-count", len(ops)),
+			attribute.Int("max-concurrency", exe.maxConcurrency),
+		),
+	)
+
+	for i, op := range ops {
+		// Wait for a free slot. Stop starting new operands if the step has
+		// been cancelled.
+		select {
+		case sem <- struct{}{}:
+		case <-stepCtx.Done():
+		}
+		if stepCtx.Err() != nil {
+			for j, blocked := range ops[i:] {
+				reports[i+j] = OperandReport{Name: blocked.Name(), Step: step, State: OperandBlocked}
+			}
+			break
+		}
 
-	// Check the result channel, if it contains any result, return a result
-	// object.
-	foundResult := false
-	if len(resultChan) > 0 {
-		foundResult = true
+		span.AddEvent(
+			"Executing operand",
+			trace.WithAttributes(attribute.String("operand-name", op.Name())),
+		)
+
+		wg.Add(1)
+		go func(i int, op operand.Operand) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			reports[i] = exe.runOperand(step, op, call, stepCtx, obj, ownerRef)
+			if reports[i].State == OperandFailed && blockers[op.Name()] {
+				span.AddEvent(
+					"Blocking operand failed, cancelling siblings",
+					trace.WithAttributes(attribute.String("operand-name", op.Name())),
+				)
+				cancel()
+			}
+		}(i, op)
 	}
-	if foundResult {
-		result = &ctrl.Result{}
+	wg.Wait()
+
+	// The operands interrupted by the cancellation of the step are reported
+	// as blocked. The operands that failed on their own are kept as failed.
+	failFast := stepCtx.Err() != nil && ctx.Err() == nil
+	for i := range reports {
+		r := &reports[i]
+		if failFast && r.State == OperandFailed && errors.Is(r.Error, context.Canceled) {
+			r.State = OperandBlocked
+		}
+		if r.Error != nil && r.State != OperandBlocked {
+			rerr = kerrors.NewAggregate([]error{rerr, r.Error})
+		}
 	}
 
-	span.AddEvent("Finish concurrent execution")
+	span.AddEvent("Finish bounded concurrent execution")
 
 	return
 }
 
-// operateWithWaitGroup runs the given function f and calls done on the wait
+// operateWithWaitGroup runs the given operand and calls done on the wait
 // group at the end. This is a goroutine function used for running the operands
-// concurrently. The result from events and errors from the execution are
-// communicated via the respective channels.
+// concurrently. The report of the execution is written to the given report.
 func (exe *Executor) operateWithWaitGroup(
 	wg *sync.WaitGroup,
-	resultChan chan ctrl.Result,
-	failedOperandChan chan string,
-	errChan chan error,
-	f func(context.Context, client.Object, metav1.OwnerReference) (eventv1.ReconcilerEvent, error),
+	report *OperandReport,
+	step int,
+	op operand.Operand,
+	call operand.OperandRunCall,
 	ctx context.Context,
 	obj client.Object,
 	ownerRef metav1.OwnerReference,
-	operandName string,
 ) {
 	defer wg.Done()
 
-	event, err := f(ctx, obj, ownerRef)
-	if err != nil {
-		errChan <- err
-		failedOperandChan <- operandName
+	*report = exe.runOperand(step, op, call, ctx, obj, ownerRef)
+}
+
+// runOperand runs the given call on an operand and returns a report of the
+// execution. The event returned by the call is recorded and is used to
+// determine if a change took place. If the operand has a timeout, the call is
+// cancelled and the operand is reported as failed once the timeout expires.
+// In dry-run mode, the changes recorded by the operand are added to the
+// report.
+func (exe *Executor) runOperand(
+	step int,
+	op operand.Operand,
+	call operand.OperandRunCall,
+	ctx context.Context,
+	obj client.Object,
+	ownerRef metav1.OwnerReference,
+) OperandReport {
+	ctx, span, log := exe.inst.Start(ctx, "run-operand")
+	defer span.End()
+
+	report := OperandReport{Name: op.Name(), Step: step}
+
+	timeout := exe.timeoutFor(op)
+	span.SetAttributes(
+		attribute.String("operand-name", op.Name()),
+		attribute.Int64("timeout", int64(timeout)),
+	)
+
+	// In dry-run mode, the changes of the operand are recorded separately to
+	// be reported with the operand.
+	parentChanges := dryrun.RecorderFrom(ctx)
+	var changes *dryrun.Recorder
+	if parentChanges != nil {
+		changes = dryrun.NewRecorder()
+		ctx = dryrun.WithRecorder(ctx, changes)
+	}
+
+	start := time.Now()
+	event, err := exe.callWithTimeout(ctx, timeout, op, call, obj, ownerRef)
+	report.Duration = time.Since(start)
+	report.Error = err
+
+	if changes != nil {
+		report.Changes = changes.Changes()
+		for _, c := range report.Changes {
+			parentChanges.Record(c)
+		}
+	}
+
+	callName := runCallName(call)
+	opLabel := exe.operandLabel(op)
+	metrics.OperandDuration.WithLabelValues(exe.name, opLabel, callName).Observe(report.Duration.Seconds())
+
+	var perr *tkctrl.PanicError
+	switch {
+	case err == nil:
+		report.State = OperandSucceeded
+		report.Ready = true
+	case errors.Is(err, operand.ErrNotReady):
+		report.State = OperandNotReady
+		metrics.OperandNotReady.WithLabelValues(exe.name, opLabel).Inc()
+	case errors.As(err, &perr):
+		report.State = OperandFailed
+		metrics.OperandFailures.WithLabelValues(exe.name, opLabel, callName, "panic").Inc()
+		tkctrl.RecordPanic(span, metrics.ComponentOperand, exe.name, perr)
+		log.Error(err, "operand panicked", "stack", perr.Stack)
+		// The events are not recorded in dry-run mode.
+		if changes == nil {
+			exe.recorder.Eventf(obj, eventv1.K8sEventTypeWarning, ReasonOperandPanic,
+				"Operand %q panicked: %v", op.Name(), perr.Value)
+		}
+	default:
+		class := metrics.ErrorClass(err)
+		switch {
+		case errors.Is(err, operand.ErrTimeout):
+			report.TimedOut = true
+			class = "timeout"
+			span.AddEvent("Operand timed out")
+		case errors.Is(err, operand.ErrInFlight):
+			class = "in-flight"
+			span.AddEvent("Operand still running")
+		}
+		report.State = OperandFailed
+		metrics.OperandFailures.WithLabelValues(exe.name, opLabel, callName, class).Inc()
+		span.RecordError(err)
 	}
 
-	// Event is used to determine if a change took place. Send a result to the
-	// result channel when an event is received.
+	// The events are not recorded in dry-run mode.
 	if event != nil {
-		event.Record(exe.recorder)
-		resultChan <- ctrl.Result{}
+		if changes == nil {
+			event.Record(exe.recorder)
+		}
+		report.Changed = true
+	}
+
+	return report
+}
+
+// operandLabel returns the operand label of the metrics of the given operand,
+// its name, or metrics.OperandOther once the maximum number of operand labels
+// is reached.
+func (exe *Executor) operandLabel(op operand.Operand) string {
+	exe.operandLabelsMu.Lock()
+	defer exe.operandLabelsMu.Unlock()
+
+	name := op.Name()
+	if exe.operandLabels[name] {
+		return name
+	}
+	if len(exe.operandLabels) >= exe.maxOperandLabels {
+		return metrics.OperandOther
+	}
+	exe.operandLabels[name] = true
+	return name
+}
+
+// runCallName returns the name of the given OperandRunCall, used only as the
+// call label of the operand metrics.
+func runCallName(call operand.OperandRunCall) string {
+	switch reflect.ValueOf(call).Pointer() {
+	case reflect.ValueOf(operand.CallEnsure).Pointer():
+		return metrics.CallEnsure
+	case reflect.ValueOf(operand.CallCleanup).Pointer():
+		return metrics.CallDelete
+	}
+	return "custom"
+}
+
+// timeoutFor returns the timeout of the given operand. The operand timeout
+// takes precedence over the executor default timeout.
+func (exe *Executor) timeoutFor(op operand.Operand) time.Duration {
+	if t, ok := op.(operand.Timeouter); ok && t.Timeout() > 0 {
+		return t.Timeout()
 	}
+	return exe.operandTimeout
+}
+
+// callWithTimeout calls the given call on the operand with a context that's
+// cancelled after the timeout. The call runs in a separate goroutine so that
+// an operand that doesn't respect the context cancellation doesn't block the
+// execution. The result of such a call, after the timeout, is discarded and
+// the operand isn't called again for the same object, failing with
+// operand.ErrInFlight, until the call returns. A zero timeout calls the
+// operand directly without any timeout. A panic in the call is recovered and
+// returned as a controller.PanicError.
+func (exe *Executor) callWithTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+	op operand.Operand,
+	call operand.OperandRunCall,
+	obj client.Object,
+	ownerRef metav1.OwnerReference,
+) (eventv1.ReconcilerEvent, error) {
+	if timeout <= 0 {
+		return safeCall(ctx, op, call, obj, ownerRef)
+	}
+
+	key := string(obj.GetUID()) + "/" + op.Name()
+	if _, running := exe.inFlight.LoadOrStore(key, struct{}{}); running {
+		return nil, fmt.Errorf("operand %q: %w", op.Name(), operand.ErrInFlight)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type callResult struct {
+		event eventv1.ReconcilerEvent
+		err   error
+	}
+	// Buffered to let the goroutine exit after a timeout.
+	resultChan := make(chan callResult, 1)
+
+	go func() {
+		event, err := safeCall(ctx, op, call, obj, ownerRef)
+		// Done before sending the result for the operand to be callable
+		// again once callWithTimeout returns.
+		exe.inFlight.Delete(key)
+		resultChan <- callResult{event: event, err: err}
+	}()
+
+	select {
+	case r := <-resultChan:
+		// The operand may return with the context error after the timeout.
+		if r.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return r.event, fmt.Errorf("operand %q: %w after %s: %v", op.Name(), operand.ErrTimeout, timeout, r.err)
+		}
+		return r.event, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("operand %q: %w after %s", op.Name(), operand.ErrTimeout, timeout)
+		}
+		return nil, fmt.Errorf("operand %q: %w", op.Name(), ctx.Err())
+	}
+}
+
+// safeCall calls the given call on the operand and recovers from a panic in
+// the call, returning it as a controller.PanicError. Without it, a panic in an
+// operand running in a goroutine of the executor would crash the manager.
+func safeCall(
+	ctx context.Context,
+	op operand.Operand,
+	call operand.OperandRunCall,
+	obj client.Object,
+	ownerRef metav1.OwnerReference,
+) (event eventv1.ReconcilerEvent, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			event = nil
+			err = fmt.Errorf("operand %q: %w", op.Name(), tkctrl.NewPanicError(r))
+		}
+	}()
+	return call(op)(ctx, obj, ownerRef)
 }
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+	"github.com/ondat/operator-toolkit/operator/v1/operand/mocks"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook/order"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
+)
+
+func TestExecuteOperandsCleanupNotExecuted(t *testing.T) {
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	mA := mocks.NewMockOperand(mockctrl)
+	mA.EXPECT().Name().Return("opA").AnyTimes()
+	mA.EXPECT().RequeueStrategy().Return(operand.RequeueOnError).AnyTimes()
+	mA.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mB := mocks.NewMockOperand(mockctrl)
+	mB.EXPECT().Name().Return("opB").AnyTimes()
+	mB.EXPECT().RequeueStrategy().Return(operand.RequeueOnError).AnyTimes()
+
+	// A cleanup call wrapping CallCleanup is still a cleanup.
+	wrappedCleanup := func(op operand.Operand) func(context.Context, client.Object, metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+		return operand.CallCleanup(op)
+	}
+
+	obj := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationSkip: "opB"},
+		},
+	}
+	exe := NewExecutor(Serial, record.NewFakeRecorder(10))
+	ops := order.OperandOrder{{mA, mB}}
+	result, report, err := exe.ExecuteOperands(ops, order.BlockingOperands{}, wrappedCleanup, operand.Cleanup, context.Background(), obj, metav1.OwnerReference{})
+	assert.ErrorIs(t, err, ErrCleanupNotExecuted)
+	assert.True(t, result.Requeue)
+	assert.Equal(t, []string{"opB"}, report.InState(OperandSkipped))
+}
+
+func TestExecutorOperandLabel(t *testing.T) {
+	mockctrl := gomock.NewController(t)
+	defer mockctrl.Finish()
+
+	newOperand := func(name string) operand.Operand {
+		m := mocks.NewMockOperand(mockctrl)
+		m.EXPECT().Name().Return(name).AnyTimes()
+		return m
+	}
+	opA, opB, opC := newOperand("opA"), newOperand("opB"), newOperand("opC")
+
+	exe := NewExecutor(Serial, record.NewFakeRecorder(10), WithMaxOperandLabels(2))
+	assert.Equal(t, "opA", exe.operandLabel(opA))
+	assert.Equal(t, "opB", exe.operandLabel(opB))
+	// The operands past the limit share the same label.
+	assert.Equal(t, metrics.OperandOther, exe.operandLabel(opC))
+	assert.Equal(t, "opA", exe.operandLabel(opA))
+}
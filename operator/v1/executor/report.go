@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+)
+
+// OperandState is the state of an operand after an execution.
+type OperandState string
+
+const (
+	// OperandSucceeded is the state of an operand that was executed
+	// successfully.
+	OperandSucceeded OperandState = "Succeeded"
+	// OperandFailed is the state of an operand whose execution returned an
+	// error.
+	OperandFailed OperandState = "Failed"
+	// OperandNotReady is the state of an operand that was executed but
+	// failed the readiness check.
+	OperandNotReady OperandState = "NotReady"
+	// OperandBlocked is the state of an operand that was not executed
+	// because a blocking operand failed before it.
+	OperandBlocked OperandState = "Blocked"
+	// OperandPending is the state of an operand that was not executed
+	// because the execution stopped early for a requeue. It's executed in a
+	// subsequent execution.
+	OperandPending OperandState = "Pending"
+	// OperandDisabled is the state of an operand that is disabled for the
+	// parent object and was cleaned up successfully.
+	OperandDisabled OperandState = "Disabled"
+	// OperandRemoved is the state of an operand that is no longer part of
+	// the operands of the parent object and was cleaned up successfully.
+	OperandRemoved OperandState = "Removed"
+	// OperandSkipped is the state of an operand that was not executed
+	// because it's skipped by the control annotations of the parent object.
+	OperandSkipped OperandState = "Skipped"
+	// OperandPaused is the state of an operand that was not executed
+	// because the execution is paused before its step by the control
+	// annotations of the parent object.
+	OperandPaused OperandState = "Paused"
+	// OperandDryRunNotSupported is the state of an operand that was not
+	// executed in dry-run mode because it doesn't implement
+	// operand.DryRunner.
+	OperandDryRunNotSupported OperandState = "DryRunNotSupported"
+)
+
+// OperandReport is the report of the execution of a single operand.
+type OperandReport struct {
+	// Name is the name of the operand.
+	Name string
+	// Step is the index of the step of the operand in the OperandOrder.
+	Step int
+	// State is the state of the operand after the execution.
+	State OperandState
+	// Ready tells if the operand was executed and is ready.
+	Ready bool
+	// Changed tells if the operand applied a change, based on the returned
+	// event.
+	Changed bool
+	// TimedOut tells if the operand execution was cancelled after its
+	// timeout.
+	TimedOut bool
+	// Duration is the duration of the operand execution.
+	Duration time.Duration
+	// Error is the error returned by the operand execution, if any.
+	Error error
+	// Changes are the changes that the operand would have made, recorded
+	// in dry-run mode.
+	Changes []dryrun.Change
+}
+
+// ExecutionReport is the report of an execution of operands. It contains the
+// reports of all the operands in the order of execution.
+type ExecutionReport struct {
+	Operands []OperandReport
+}
+
+// Get returns the report of the operand with the given name, or nil if not
+// found.
+func (r *ExecutionReport) Get(name string) *OperandReport {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Operands {
+		if r.Operands[i].Name == name {
+			return &r.Operands[i]
+		}
+	}
+	return nil
+}
+
+// InState returns the names of the operands in the given state.
+func (r *ExecutionReport) InState(state OperandState) []string {
+	names := []string{}
+	if r == nil {
+		return names
+	}
+	for _, op := range r.Operands {
+		if op.State == state {
+			names = append(names, op.Name)
+		}
+	}
+	return names
+}
+
+// Changes returns the changes of all the operands, recorded in dry-run mode,
+// in the order of the operand reports.
+func (r *ExecutionReport) Changes() []dryrun.Change {
+	changes := []dryrun.Change{}
+	if r == nil {
+		return changes
+	}
+	for _, op := range r.Operands {
+		changes = append(changes, op.Changes...)
+	}
+	return changes
+}
+
+// Merge adds the operand reports of the given report to the report.
+func (r *ExecutionReport) Merge(other *ExecutionReport) {
+	if other == nil {
+		return
+	}
+	r.add(other.Operands...)
+}
+
+// add adds the given operand reports to the report.
+func (r *ExecutionReport) add(reports ...OperandReport) {
+	r.Operands = append(r.Operands, reports...)
+}
+
+// OperandStatuses returns the report as a list of OperandStatus, sorted by
+// step and name, that can be set in the status of the parent object.
+func (r *ExecutionReport) OperandStatuses() []OperandStatus {
+	statuses := []OperandStatus{}
+	if r == nil {
+		return statuses
+	}
+	for _, op := range r.Operands {
+		s := OperandStatus{
+			Name:  op.Name,
+			Step:  op.Step,
+			State: string(op.State),
+			Ready: op.Ready,
+		}
+		if op.Error != nil {
+			s.Message = op.Error.Error()
+		}
+		statuses = append(statuses, s)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].Step != statuses[j].Step {
+			return statuses[i].Step < statuses[j].Step
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// OperandStatus is the status of an operand that can be embedded in the
+// status of a parent object, usually as a list in status.operands.
+type OperandStatus struct {
+	// Name is the name of the operand.
+	Name string `json:"name"`
+	// Step is the index of the execution step of the operand.
+	Step int `json:"step"`
+	// State is the state of the operand after the last execution.
+	State string `json:"state"`
+	// Ready tells if the operand is ready.
+	Ready bool `json:"ready"`
+	// Message is a human readable message about the state of the operand,
+	// usually the error from the last execution.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DeepCopyInto copies the receiver into out.
+func (in *OperandStatus) DeepCopyInto(out *OperandStatus) {
+	*out = *in
+}
+
+// DeepCopy creates a new deep copy of the OperandStatus.
+func (in *OperandStatus) DeepCopy() *OperandStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperandStatus)
+	in.DeepCopyInto(out)
+	return out
+}
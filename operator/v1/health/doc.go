@@ -0,0 +1,6 @@
+// Package health keeps the last health of the operands of each parent object
+// in memory, from the execution reports of the operator. It exposes the
+// health as a debug HTTP handler that serves the health as JSON and as a
+// healthz.Checker, that can be registered as a readiness check of the manager
+// explicitly.
+package health
@@ -0,0 +1,230 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+// DefaultPath is the default path of the debug handler.
+const DefaultPath = "/debug/operands"
+
+// OperandHealth is the health of an operand of a parent object.
+type OperandHealth struct {
+	// Name is the name of the operand.
+	Name string `json:"name"`
+	// State is the state of the operand after its last execution.
+	State executor.OperandState `json:"state"`
+	// Ready tells if the operand was ready after its last execution.
+	Ready bool `json:"ready"`
+	// Message is the error of the last execution, if any.
+	Message string `json:"message,omitempty"`
+	// LastExecuted is the time of the last execution of the operand.
+	LastExecuted time.Time `json:"lastExecuted"`
+}
+
+// Healthy tells if the operand is healthy.
+func (o OperandHealth) Healthy() bool {
+	switch o.State {
+	case executor.OperandFailed, executor.OperandNotReady, executor.OperandBlocked:
+		return false
+	}
+	return true
+}
+
+// ObjectHealth is the health of the operands of a parent object.
+type ObjectHealth struct {
+	// Namespace is the namespace of the parent object.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the parent object.
+	Name string `json:"name"`
+	// UID is the UID of the parent object.
+	UID types.UID `json:"uid"`
+	// Healthy tells if all the operands of the object are healthy.
+	Healthy bool `json:"healthy"`
+	// Operands is the health of the operands, sorted by name.
+	Operands []OperandHealth `json:"operands"`
+	// LastUpdated is the time of the last update of the health.
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// key returns the namespaced name of the object.
+func (o ObjectHealth) key() string {
+	return types.NamespacedName{Namespace: o.Namespace, Name: o.Name}.String()
+}
+
+// Tracker keeps the last health of the operands of the parent objects. It's
+// safe for concurrent use.
+type Tracker struct {
+	mu      sync.RWMutex
+	objects map[types.UID]*ObjectHealth
+	now     func() time.Time
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		objects: map[types.UID]*ObjectHealth{},
+		now:     time.Now,
+	}
+}
+
+// Update updates the health of the operands of the object from the given
+// execution report. The operands that weren't executed keep their last
+// health, if known. The removed and disabled operands are dropped.
+func (t *Tracker) Update(obj client.Object, report *executor.ExecutionReport) {
+	if report == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	previous := map[string]OperandHealth{}
+	if h, ok := t.objects[obj.GetUID()]; ok {
+		for _, op := range h.Operands {
+			previous[op.Name] = op
+		}
+	}
+
+	operands := map[string]OperandHealth{}
+	for _, r := range report.Operands {
+		switch r.State {
+		case executor.OperandRemoved, executor.OperandDisabled:
+			continue
+		case executor.OperandPending, executor.OperandPaused, executor.OperandSkipped, executor.OperandBlocked:
+			if prev, ok := previous[r.Name]; ok {
+				operands[r.Name] = prev
+				continue
+			}
+		}
+		op := OperandHealth{Name: r.Name, State: r.State, Ready: r.Ready, LastExecuted: now}
+		if r.Error != nil {
+			op.Message = r.Error.Error()
+		}
+		operands[r.Name] = op
+	}
+
+	h := &ObjectHealth{
+		Namespace:   obj.GetNamespace(),
+		Name:        obj.GetName(),
+		UID:         obj.GetUID(),
+		Healthy:     true,
+		Operands:    make([]OperandHealth, 0, len(operands)),
+		LastUpdated: now,
+	}
+	for _, op := range operands {
+		h.Operands = append(h.Operands, op)
+		h.Healthy = h.Healthy && op.Healthy()
+	}
+	sort.Slice(h.Operands, func(i, j int) bool { return h.Operands[i].Name < h.Operands[j].Name })
+
+	t.objects[obj.GetUID()] = h
+}
+
+// Forget removes the health of the object, usually once it's deleted.
+func (t *Tracker) Forget(obj client.Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.objects, obj.GetUID())
+}
+
+// Objects returns the health of all the objects, sorted by namespace and
+// name.
+func (t *Tracker) Objects() []ObjectHealth {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	result := make([]ObjectHealth, 0, len(t.objects))
+	for _, h := range t.objects {
+		c := *h
+		c.Operands = append([]OperandHealth{}, h.Operands...)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].key() < result[j].key() })
+	return result
+}
+
+// Unhealthy returns the health of the unhealthy objects, sorted by namespace
+// and name.
+func (t *Tracker) Unhealthy() []ObjectHealth {
+	result := []ObjectHealth{}
+	for _, h := range t.Objects() {
+		if !h.Healthy {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// Checker is a healthz.Checker that fails if any object is unhealthy. The
+// error names the unhealthy objects and operands.
+func (t *Tracker) Checker(_ *http.Request) error {
+	unhealthy := t.Unhealthy()
+	if len(unhealthy) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(unhealthy))
+	for _, h := range unhealthy {
+		ops := []string{}
+		for _, op := range h.Operands {
+			if !op.Healthy() {
+				ops = append(ops, fmt.Sprintf("%s: %s", op.Name, op.State))
+			}
+		}
+		msgs = append(msgs, fmt.Sprintf("%s (%s)", h.key(), strings.Join(ops, ", ")))
+	}
+	return fmt.Errorf("unhealthy objects: %s", strings.Join(msgs, "; "))
+}
+
+var _ healthz.Checker = (&Tracker{}).Checker
+
+// ServeHTTP implements http.Handler. It serves the health of the objects as
+// JSON. With the query parameter "unhealthy=true", only the unhealthy
+// objects are served.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	objects := t.Objects()
+	if r.URL.Query().Get("unhealthy") == "true" {
+		objects = t.Unhealthy()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(objects); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// AddToManager registers the debug handler on the metrics server of the
+// manager at the given path.
+func (t *Tracker) AddToManager(mgr manager.Manager, path string) error {
+	if err := mgr.AddMetricsExtraHandler(path, t); err != nil {
+		return fmt.Errorf("failed to add debug handler %q: %w", path, err)
+	}
+	return nil
+}
+
+// AddReadyzCheck registers the Checker as a readiness check of the manager
+// with the given name. The manager is then not ready while any object is
+// unhealthy, which is only suitable for operators that can't serve anything
+// until all their objects are healthy.
+func (t *Tracker) AddReadyzCheck(mgr manager.Manager, name string) error {
+	if err := mgr.AddReadyzCheck(name, t.Checker); err != nil {
+		return fmt.Errorf("failed to add readiness check %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
+)
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+
+	foo := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"}}
+	bar := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default", UID: "bar-uid"}}
+
+	tracker.Update(foo, &executor.ExecutionReport{Operands: []executor.OperandReport{
+		{Name: "a", State: executor.OperandSucceeded, Ready: true},
+		{Name: "b", State: executor.OperandSucceeded, Ready: true},
+	}})
+	tracker.Update(bar, &executor.ExecutionReport{Operands: []executor.OperandReport{
+		{Name: "a", State: executor.OperandSucceeded, Ready: true},
+		{Name: "b", State: executor.OperandNotReady, Error: errors.New("not ready")},
+	}})
+
+	assert.Len(t, tracker.Objects(), 2)
+	unhealthy := tracker.Unhealthy()
+	assert.Len(t, unhealthy, 1)
+	assert.Equal(t, "bar", unhealthy[0].Name)
+	assert.Equal(t, "not ready", unhealthy[0].Operands[1].Message)
+	assert.EqualError(t, tracker.Checker(nil), "unhealthy objects: default/bar (b: NotReady)")
+
+	// The operands that aren't executed keep their last health and the
+	// removed operands are dropped.
+	later := now.Add(time.Minute)
+	tracker.now = func() time.Time { return later }
+	tracker.Update(foo, &executor.ExecutionReport{Operands: []executor.OperandReport{
+		{Name: "a", State: executor.OperandSkipped},
+		{Name: "b", State: executor.OperandRemoved},
+		{Name: "c", State: executor.OperandFailed, Error: errors.New("boom")},
+	}})
+	h := tracker.Objects()[1]
+	assert.Equal(t, "foo", h.Name)
+	assert.False(t, h.Healthy)
+	assert.Equal(t, []OperandHealth{
+		{Name: "a", State: executor.OperandSucceeded, Ready: true, LastExecuted: now},
+		{Name: "c", State: executor.OperandFailed, Message: "boom", LastExecuted: later},
+	}, h.Operands)
+
+	tracker.Forget(foo)
+	tracker.Forget(bar)
+	assert.Empty(t, tracker.Objects())
+	assert.Nil(t, tracker.Checker(nil))
+}
+
+func TestTrackerServeHTTP(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Update(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "foo-uid"}}, &executor.ExecutionReport{Operands: []executor.OperandReport{
+		{Name: "a", State: executor.OperandSucceeded, Ready: true},
+	}})
+	tracker.Update(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bar", UID: "bar-uid"}}, &executor.ExecutionReport{Operands: []executor.OperandReport{
+		{Name: "a", State: executor.OperandFailed},
+	}})
+
+	cases := []struct {
+		query     string
+		wantNames []string
+	}{
+		{query: "", wantNames: []string{"bar", "foo"}},
+		{query: "?unhealthy=true", wantNames: []string{"bar"}},
+	}
+
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		tracker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DefaultPath+tc.query, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		got := []ObjectHealth{}
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		names := []string{}
+		for _, h := range got {
+			names = append(names, h.Name)
+		}
+		assert.Equal(t, tc.wantNames, names, tc.query)
+	}
+}
@@ -0,0 +1,5 @@
+// Package declarative provides an operand that applies a declarative
+// kustomize package, loaded from a channel, as a single operand of an
+// operator. The package is rendered per parent object with parent-derived
+// transforms and is ready once all the rendered objects are ready.
+package declarative
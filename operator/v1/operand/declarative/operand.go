@@ -0,0 +1,409 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	tkdeclarative "github.com/ondat/operator-toolkit/declarative"
+	"github.com/ondat/operator-toolkit/declarative/kubectl"
+	"github.com/ondat/operator-toolkit/declarative/kustomize"
+	"github.com/ondat/operator-toolkit/declarative/transform"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/object"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// Operand is an operand that renders a kustomize package from a manifest
+// filesystem for a parent object and applies it. The owner reference passed
+// to Ensure is set on all the rendered objects and the objects are placed in
+// the namespace of the parent object.
+type Operand struct {
+	name            string
+	packageName     string
+	fs              filesys.FileSystem
+	client          client.Client
+	kubectl         kubectl.KubectlClient
+	requires        []string
+	cleanupRequires []string
+	requeueStrategy operand.RequeueStrategy
+
+	// manifestTransform, transforms and mutations return the parent-derived
+	// transforms and kustomize mutations of the package.
+	manifestTransform func(client.Object) transform.ManifestTransform
+	transforms        func(client.Object) []transform.TransformFunc
+	mutations         func(client.Object) []kustomize.MutateFunc
+
+	// readyCheck checks the readiness of a single rendered object.
+	readyCheck func(context.Context, client.Object) (bool, error)
+
+	// ownershipLabels enables setting the ownership labels of the parent
+	// object on the rendered objects.
+	ownershipLabels bool
+}
+
+var _ operand.Operand = &Operand{}
+
+// OperandOption is used to configure Operand.
+type OperandOption func(*Operand)
+
+// WithRequires sets the operands that the operand requires during ensure.
+func WithRequires(requires ...string) OperandOption {
+	return func(o *Operand) {
+		o.requires = requires
+	}
+}
+
+// WithCleanupRequires sets the operands that the operand requires during
+// cleanup.
+func WithCleanupRequires(requires ...string) OperandOption {
+	return func(o *Operand) {
+		o.cleanupRequires = requires
+	}
+}
+
+// WithRequeueStrategy sets the requeue strategy of the operand.
+func WithRequeueStrategy(strategy operand.RequeueStrategy) OperandOption {
+	return func(o *Operand) {
+		o.requeueStrategy = strategy
+	}
+}
+
+// WithKubectlClient sets the kubectl client used to apply and delete the
+// package.
+func WithKubectlClient(k kubectl.KubectlClient) OperandOption {
+	return func(o *Operand) {
+		o.kubectl = k
+	}
+}
+
+// WithManifestTransform sets a function that returns the manifest transforms
+// of the package for a parent object.
+func WithManifestTransform(f func(parent client.Object) transform.ManifestTransform) OperandOption {
+	return func(o *Operand) {
+		o.manifestTransform = f
+	}
+}
+
+// WithTransforms sets a function that returns the transforms applied to all
+// the manifests of the package for a parent object, in addition to setting
+// the owner reference.
+func WithTransforms(f func(parent client.Object) []transform.TransformFunc) OperandOption {
+	return func(o *Operand) {
+		o.transforms = f
+	}
+}
+
+// WithMutations sets a function that returns the kustomize mutations of the
+// package for a parent object, in addition to setting the namespace.
+func WithMutations(f func(parent client.Object) []kustomize.MutateFunc) OperandOption {
+	return func(o *Operand) {
+		o.mutations = f
+	}
+}
+
+// WithReadyCheck sets the ready check of the rendered objects. By default,
+// operand.IsResourceReady is used.
+func WithReadyCheck(f func(ctx context.Context, live client.Object) (bool, error)) OperandOption {
+	return func(o *Operand) {
+		o.readyCheck = f
+	}
+}
+
+// WithOwnershipLabels sets the ownership labels of the parent object on all
+// the rendered objects, in addition to the owner reference. They allow
+// tracking the rendered objects that can't have an owner reference to the
+// parent, like cluster-scoped objects, see object.SetOwnershipLabels.
+func WithOwnershipLabels() OperandOption {
+	return func(o *Operand) {
+		o.ownershipLabels = true
+	}
+}
+
+// NewOperand creates an Operand for the given package in the manifest
+// filesystem. The filesystem is usually loaded with
+// loader.NewLoadedManifestFileSystem. The client is used to check the
+// readiness of the rendered objects.
+func NewOperand(name string, c client.Client, packageName string, fs filesys.FileSystem, opts ...OperandOption) *Operand {
+	o := &Operand{
+		name:            name,
+		client:          c,
+		packageName:     packageName,
+		fs:              fs,
+		requeueStrategy: operand.RequeueOnError,
+		readyCheck: func(ctx context.Context, live client.Object) (bool, error) {
+			return operand.IsResourceReady(live)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Name implements the Operand interface.
+func (o *Operand) Name() string { return o.name }
+
+// Requires implements the Operand interface.
+func (o *Operand) Requires() []string { return o.requires }
+
+// CleanupRequires implements the Operand interface.
+func (o *Operand) CleanupRequires() []string { return o.cleanupRequires }
+
+// RequeueStrategy implements the Operand interface.
+func (o *Operand) RequeueStrategy() operand.RequeueStrategy { return o.requeueStrategy }
+
+// PostReady implements the Operand interface.
+func (o *Operand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+// SupportsDryRun implements the operand.DryRunner interface. The rendered
+// objects are written through dryrun.ClientFor in dry-run mode.
+func (o *Operand) SupportsDryRun() bool { return true }
+
+// Ensure implements the Operand interface. It renders the package for the
+// parent object and applies it. A PackageEvent is returned when the apply
+// created or changed any of the rendered objects, detected through their
+// resource versions.
+func (o *Operand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	b, err := o.builder(obj, ownerRef)
+	if err != nil {
+		return nil, err
+	}
+	if dryrun.IsDryRun(ctx) {
+		return nil, o.dryRun(ctx, b, false)
+	}
+
+	objs, err := ParseManifest(b.Manifest())
+	if err != nil {
+		return nil, err
+	}
+	before, err := o.resourceVersions(ctx, objs)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.Apply(ctx); err != nil {
+		return nil, err
+	}
+	after, err := o.resourceVersions(ctx, objs)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	for i, rendered := range objs {
+		if before[i] != after[i] {
+			changed = append(changed, rendered.GetKind()+" "+rendered.GetName())
+		}
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	return &PackageEvent{Object: obj, Package: o.packageName, Changed: changed}, nil
+}
+
+// resourceVersions returns the resource versions of the live objects of the
+// given rendered objects, in the same order. The resource version of a
+// missing object is empty.
+func (o *Operand) resourceVersions(ctx context.Context, objs []*unstructured.Unstructured) ([]string, error) {
+	versions := make([]string, len(objs))
+	for i, rendered := range objs {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(rendered.GroupVersionKind())
+		if err := o.client.Get(ctx, client.ObjectKeyFromObject(rendered), live); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s %q: %w", rendered.GetKind(), rendered.GetName(), err)
+		}
+		versions[i] = live.GetResourceVersion()
+	}
+	return versions, nil
+}
+
+// Delete implements the Operand interface. It renders the package for the
+// parent object and deletes it.
+func (o *Operand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	b, err := o.builder(obj, metav1.OwnerReference{})
+	if err != nil {
+		return nil, err
+	}
+	if dryrun.IsDryRun(ctx) {
+		return nil, o.dryRun(ctx, b, true)
+	}
+	return nil, b.Delete(ctx)
+}
+
+// dryRun creates, updates or deletes the rendered objects with a dry-run
+// client to record the changes, since kubectl doesn't record them. The apply
+// of an existing object is approximated with an update of the rendered
+// object.
+func (o *Operand) dryRun(ctx context.Context, b *tkdeclarative.Builder, remove bool) error {
+	objs, err := ParseManifest(b.Manifest())
+	if err != nil {
+		return err
+	}
+
+	c := dryrun.ClientFor(ctx, o.client)
+	for _, rendered := range objs {
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(rendered.GroupVersionKind())
+		if err := o.client.Get(ctx, client.ObjectKeyFromObject(rendered), live); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get %s %q: %w", rendered.GetKind(), rendered.GetName(), err)
+			}
+			live = nil
+		}
+
+		var err error
+		switch {
+		case live == nil && !remove:
+			err = c.Create(ctx, rendered)
+		case live != nil && remove:
+			err = c.Delete(ctx, live)
+		case live != nil:
+			rendered.SetResourceVersion(live.GetResourceVersion())
+			err = c.Update(ctx, rendered)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to dry-run %s %q: %w", rendered.GetKind(), rendered.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// ReadyCheck implements the Operand interface. It renders the package for
+// the parent object and checks the readiness of all the rendered objects.
+func (o *Operand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	b, err := o.builder(obj, metav1.OwnerReference{})
+	if err != nil {
+		return false, err
+	}
+
+	objs, err := ParseManifest(b.Manifest())
+	if err != nil {
+		return false, err
+	}
+
+	for _, rendered := range objs {
+		live, err := o.liveObject(ctx, rendered)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		ready, err := o.readyCheck(ctx, live)
+		if err != nil || !ready {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// builder returns a declarative builder of the package for the given parent
+// object.
+func (o *Operand) builder(obj client.Object, ownerRef metav1.OwnerReference) (*tkdeclarative.Builder, error) {
+	transforms := []transform.TransformFunc{}
+	if ownerRef.UID != "" {
+		transforms = append(transforms, transform.SetOwnerReference([]metav1.OwnerReference{ownerRef}))
+		if o.ownershipLabels {
+			transforms = append(transforms, transform.SetOwnershipLabels(object.OwnerFromReference(ownerRef, obj.GetNamespace())))
+		}
+	}
+	if o.transforms != nil {
+		transforms = append(transforms, o.transforms(obj)...)
+	}
+
+	mutations := []kustomize.MutateFunc{}
+	if obj.GetNamespace() != "" {
+		mutations = append(mutations, kustomize.AddNamespace(obj.GetNamespace()))
+	}
+	if o.mutations != nil {
+		mutations = append(mutations, o.mutations(obj)...)
+	}
+
+	opts := []tkdeclarative.BuilderOption{
+		tkdeclarative.WithCommonTransforms(transforms),
+		tkdeclarative.WithKustomizeMutationFunc(mutations),
+	}
+	if o.manifestTransform != nil {
+		opts = append(opts, tkdeclarative.WithManifestTransform(o.manifestTransform(obj)))
+	}
+	if o.kubectl != nil {
+		opts = append(opts, tkdeclarative.WithKubectlClient(o.kubectl))
+	}
+
+	b, err := tkdeclarative.NewBuilder(o.packageName, o.fs, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build package %q: %w", o.packageName, err)
+	}
+	return b, nil
+}
+
+// liveObject fetches the live object of the given rendered object. A typed
+// object is used when the type is known to the client scheme, to allow the
+// readiness check of the known types.
+func (o *Operand) liveObject(ctx context.Context, rendered *unstructured.Unstructured) (client.Object, error) {
+	live := object.NewObject(o.client.Scheme(), rendered.GroupVersionKind())
+	if err := o.client.Get(ctx, client.ObjectKeyFromObject(rendered), live); err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// PackageEvent is a ReconcilerEvent recorded on the parent object when an
+// Operand apply creates or changes some of the objects of its package.
+type PackageEvent struct {
+	// Object is the parent object.
+	Object client.Object
+	// Package is the name of the applied package.
+	Package string
+	// Changed are the kinds and names of the created or changed objects.
+	Changed []string
+}
+
+var _ eventv1.ReconcilerEvent = &PackageEvent{}
+
+// Record implements eventv1.ReconcilerEvent.
+func (e *PackageEvent) Record(recorder record.EventRecorder) {
+	recorder.Event(e.Object,
+		eventv1.K8sEventTypeNormal,
+		"PackageApplied",
+		fmt.Sprintf("Applied package %s: %s", e.Package, strings.Join(e.Changed, ", ")),
+	)
+}
+
+// ParseManifest parses a multi-document YAML manifest into a list of
+// objects. Empty documents are skipped.
+func ParseManifest(manifest string) ([]*unstructured.Unstructured, error) {
+	objs := []*unstructured.Unstructured{}
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		u := &unstructured.Unstructured{}
+		if err := decoder.Decode(&u.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+		objs = append(objs, u)
+	}
+	return objs, nil
+}
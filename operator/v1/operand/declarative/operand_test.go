@@ -0,0 +1,186 @@
+package declarative
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/declarative/kustomize"
+	"github.com/ondat/operator-toolkit/declarative/loader"
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// fakeKubectl records the applied and deleted manifests. If a client is set,
+// the applied objects that don't exist are created with it.
+type fakeKubectl struct {
+	client  client.Client
+	applied string
+	deleted string
+}
+
+func (k *fakeKubectl) Apply(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	k.applied = manifest
+	if k.client == nil {
+		return nil
+	}
+	objs, err := ParseManifest(manifest)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if err := k.client.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *fakeKubectl) Delete(ctx context.Context, namespace string, manifest string, validate bool, extraArgs ...string) error {
+	k.deleted = manifest
+	return nil
+}
+
+func TestOperand(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "foo-ns", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "parent", UID: "parent-uid"}
+
+	fs, err := loader.NewLoadedManifestFileSystem("../../../../declarative/testdata/channels", "")
+	assert.Nil(t, err)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	k := &fakeKubectl{}
+
+	op := NewOperand("guestbook", cli, "guestbook", fs,
+		WithKubectlClient(k),
+		WithMutations(func(parent client.Object) []kustomize.MutateFunc {
+			return []kustomize.MutateFunc{kustomize.AddNamePrefix(parent.GetName() + "-")}
+		}),
+	)
+
+	// Ensure applies the package rendered for the parent.
+	_, err = op.Ensure(context.Background(), parent, ownerRef)
+	assert.Nil(t, err)
+
+	objs, err := ParseManifest(k.applied)
+	assert.Nil(t, err)
+	assert.Len(t, objs, 2)
+	for _, obj := range objs {
+		assert.Equal(t, "foo-ns", obj.GetNamespace())
+		assert.Contains(t, obj.GetName(), "parent-")
+		if assert.Len(t, obj.GetOwnerReferences(), 1) {
+			assert.Equal(t, ownerRef.UID, obj.GetOwnerReferences()[0].UID)
+		}
+	}
+
+	// Not ready until all the rendered objects exist.
+	ready, err := op.ReadyCheck(context.Background(), parent)
+	assert.Nil(t, err)
+	assert.False(t, ready)
+
+	assert.Nil(t, cli.Create(context.Background(), &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent-app-role", Namespace: "foo-ns"},
+	}))
+	ready, err = op.ReadyCheck(context.Background(), parent)
+	assert.Nil(t, err)
+	assert.False(t, ready)
+
+	assert.Nil(t, cli.Create(context.Background(), &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent-test-sa", Namespace: "foo-ns"},
+	}))
+	ready, err = op.ReadyCheck(context.Background(), parent)
+	assert.Nil(t, err)
+	assert.True(t, ready)
+
+	// Delete deletes the package rendered for the parent.
+	_, err = op.Delete(context.Background(), parent)
+	assert.Nil(t, err)
+	objs, err = ParseManifest(k.deleted)
+	assert.Nil(t, err)
+	assert.Len(t, objs, 2)
+}
+
+func TestOperandEnsureEvent(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "foo-ns", UID: "parent-uid"},
+	}
+
+	fs, err := loader.NewLoadedManifestFileSystem("../../../../declarative/testdata/channels", "")
+	assert.Nil(t, err)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	op := NewOperand("guestbook", cli, "guestbook", fs, WithKubectlClient(&fakeKubectl{client: cli}))
+
+	// The first apply creates the objects of the package.
+	event, err := op.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	if assert.IsType(t, &PackageEvent{}, event) {
+		assert.Equal(t, "guestbook", event.(*PackageEvent).Package)
+		assert.Len(t, event.(*PackageEvent).Changed, 2)
+	}
+
+	recorder := record.NewFakeRecorder(1)
+	event.Record(recorder)
+	assert.Contains(t, <-recorder.Events, "Normal PackageApplied Applied package guestbook: ")
+
+	// Nothing changes with the second apply.
+	event, err = op.Ensure(context.Background(), parent, metav1.OwnerReference{})
+	assert.Nil(t, err)
+	assert.Nil(t, event)
+}
+
+func TestOperandOwnershipLabels(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "foo-ns", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "parent", UID: "parent-uid"}
+
+	fs, err := loader.NewLoadedManifestFileSystem("../../../../declarative/testdata/channels", "")
+	assert.Nil(t, err)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	k := &fakeKubectl{}
+	op := NewOperand("guestbook", cli, "guestbook", fs, WithKubectlClient(k), WithOwnershipLabels())
+
+	_, err = op.Ensure(context.Background(), parent, ownerRef)
+	assert.Nil(t, err)
+
+	objs, err := ParseManifest(k.applied)
+	assert.Nil(t, err)
+	assert.Len(t, objs, 2)
+	for _, obj := range objs {
+		owner, ok := object.OwnerFromLabels(obj)
+		assert.True(t, ok)
+		assert.Equal(t, object.OwnerFromReference(ownerRef, "foo-ns"), owner)
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: foo
+---
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: bar
+`
+	objs, err := ParseManifest(manifest)
+	assert.Nil(t, err)
+	if assert.Len(t, objs, 2) {
+		assert.Equal(t, "ServiceAccount", objs[0].GetKind())
+		assert.Equal(t, "bar", objs[1].GetName())
+	}
+}
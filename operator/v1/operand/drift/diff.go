@@ -0,0 +1,74 @@
+package drift
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// DefaultIgnoreFields are the fields that are always ignored when comparing
+// objects. These fields are set by the API server and are never part of the
+// desired state.
+var DefaultIgnoreFields = []operand.FieldPath{
+	{"metadata", "creationTimestamp"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "selfLink"},
+	{"metadata", "uid"},
+	{"status"},
+}
+
+// Diff compares the live and the desired object contents and returns the
+// dot separated paths of the fields that differ, sorted. The fields in
+// DefaultIgnoreFields and the given ignore fields are not compared.
+func Diff(live, desired map[string]interface{}, ignore ...operand.FieldPath) []string {
+	live = runtime.DeepCopyJSON(live)
+	desired = runtime.DeepCopyJSON(desired)
+
+	for _, path := range append(append([]operand.FieldPath{}, DefaultIgnoreFields...), ignore...) {
+		unstructured.RemoveNestedField(live, path...)
+		unstructured.RemoveNestedField(desired, path...)
+	}
+
+	diffs := []string{}
+	diffFields(nil, live, desired, &diffs)
+	sort.Strings(diffs)
+	return diffs
+}
+
+// diffFields recursively compares the fields of two maps and appends the
+// paths of the differing fields to diffs. Lists and scalar values are
+// compared as a whole.
+func diffFields(path []string, a, b map[string]interface{}, diffs *[]string) {
+	keys := map[string]struct{}{}
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+
+	for k := range keys {
+		fieldPath := append(append([]string{}, path...), k)
+		av, aok := a[k]
+		bv, bok := b[k]
+		if aok && bok {
+			am, amok := av.(map[string]interface{})
+			bm, bmok := bv.(map[string]interface{})
+			if amok && bmok {
+				diffFields(fieldPath, am, bm, diffs)
+				continue
+			}
+			if equality.Semantic.DeepEqual(av, bv) {
+				continue
+			}
+		}
+		*diffs = append(*diffs, strings.Join(fieldPath, "."))
+	}
+}
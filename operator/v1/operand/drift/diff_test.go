@@ -0,0 +1,96 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+func TestDiff(t *testing.T) {
+	base := func() map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":            "foo",
+				"namespace":       "default",
+				"resourceVersion": "10",
+				"annotations": map[string]interface{}{
+					"foo": "bar",
+				},
+			},
+			"data": map[string]interface{}{
+				"a": "1",
+				"b": "2",
+			},
+			"list": []interface{}{"x", "y"},
+		}
+	}
+
+	testcases := []struct {
+		name    string
+		mutate  func(desired map[string]interface{})
+		ignore  []operand.FieldPath
+		wantOut []string
+	}{
+		{
+			name:    "no diff",
+			mutate:  func(d map[string]interface{}) {},
+			wantOut: []string{},
+		},
+		{
+			name: "default ignored fields",
+			mutate: func(d map[string]interface{}) {
+				d["metadata"].(map[string]interface{})["resourceVersion"] = "11"
+				d["status"] = map[string]interface{}{"phase": "Ready"}
+			},
+			wantOut: []string{},
+		},
+		{
+			name: "changed and added fields",
+			mutate: func(d map[string]interface{}) {
+				d["data"].(map[string]interface{})["a"] = "10"
+				d["data"].(map[string]interface{})["c"] = "3"
+			},
+			wantOut: []string{"data.a", "data.c"},
+		},
+		{
+			name: "removed field",
+			mutate: func(d map[string]interface{}) {
+				delete(d["data"].(map[string]interface{}), "b")
+			},
+			wantOut: []string{"data.b"},
+		},
+		{
+			name: "changed list",
+			mutate: func(d map[string]interface{}) {
+				d["list"] = []interface{}{"x"}
+			},
+			wantOut: []string{"list"},
+		},
+		{
+			name: "ignored field",
+			mutate: func(d map[string]interface{}) {
+				d["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{"foo": "baz"}
+				d["data"].(map[string]interface{})["a"] = "10"
+			},
+			ignore:  []operand.FieldPath{{"metadata", "annotations"}},
+			wantOut: []string{"data.a"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			live := base()
+			desired := base()
+			tc.mutate(desired)
+			assert.Equal(t, tc.wantOut, Diff(live, desired, tc.ignore...))
+
+			// The inputs are not modified.
+			assert.Equal(t, base(), live)
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Package drift provides a generic operand that detects the drift between the
+// desired and the live state of a target object and corrects it. The desired
+// state is described by an operand.DriftDetector. A server-side dry-run apply
+// of the desired object is compared with the live object to decide if an
+// update is needed, so that the defaults and the mutations of the API server
+// aren't reported as drift.
+package drift
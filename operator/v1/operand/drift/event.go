@@ -0,0 +1,40 @@
+package drift
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+)
+
+// ReasonDriftCorrected is the reason of the event recorded when a drift is
+// corrected.
+const ReasonDriftCorrected = "DriftCorrected"
+
+// Event is a ReconcilerEvent recorded on the parent object when the drift of
+// a target object is corrected.
+type Event struct {
+	// Object is the parent object.
+	Object client.Object
+	// Target identifies the target object, e.g. "Deployment/foo".
+	Target string
+	// Fields are the paths of the fields that drifted. It's empty when the
+	// target object was created.
+	Fields []string
+}
+
+var _ eventv1.ReconcilerEvent = &Event{}
+
+// Record implements eventv1.ReconcilerEvent.
+func (e *Event) Record(recorder record.EventRecorder) {
+	if len(e.Fields) == 0 {
+		recorder.Event(e.Object, eventv1.K8sEventTypeNormal, ReasonDriftCorrected,
+			fmt.Sprintf("Created %s", e.Target))
+		return
+	}
+	recorder.Event(e.Object, eventv1.K8sEventTypeNormal, ReasonDriftCorrected,
+		fmt.Sprintf("Corrected drift in %s: %s", e.Target, strings.Join(e.Fields, ", ")))
+}
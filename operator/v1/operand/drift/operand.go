@@ -0,0 +1,233 @@
+package drift
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	"github.com/ondat/operator-toolkit/constant"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/object"
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// Operand is a generic operand that ensures that the target object described
+// by a DriftDetector is in the desired state. The desired object is applied
+// with server-side apply only when the result of a dry-run apply differs from
+// the live object. A drift Event is returned only when a change is applied.
+type Operand struct {
+	name            string
+	client          client.Client
+	detector        operand.DriftDetector
+	requires        []string
+	cleanupRequires []string
+	requeueStrategy operand.RequeueStrategy
+	fieldManager    string
+	readyCheck      func(context.Context, client.Object) (bool, error)
+	ownershipLabels bool
+}
+
+var _ operand.Operand = &Operand{}
+
+// OperandOption is used to configure Operand.
+type OperandOption func(*Operand)
+
+// WithRequires sets the operands that the operand requires during ensure.
+func WithRequires(requires ...string) OperandOption {
+	return func(o *Operand) {
+		o.requires = requires
+	}
+}
+
+// WithCleanupRequires sets the operands that the operand requires during
+// cleanup.
+func WithCleanupRequires(requires ...string) OperandOption {
+	return func(o *Operand) {
+		o.cleanupRequires = requires
+	}
+}
+
+// WithRequeueStrategy sets the requeue strategy of the operand.
+func WithRequeueStrategy(strategy operand.RequeueStrategy) OperandOption {
+	return func(o *Operand) {
+		o.requeueStrategy = strategy
+	}
+}
+
+// WithFieldManager sets the field manager used for server-side apply.
+func WithFieldManager(name string) OperandOption {
+	return func(o *Operand) {
+		o.fieldManager = name
+	}
+}
+
+// WithReadyCheck sets the ready check of the operand. By default, the operand
+// is always ready.
+func WithReadyCheck(f func(context.Context, client.Object) (bool, error)) OperandOption {
+	return func(o *Operand) {
+		o.readyCheck = f
+	}
+}
+
+// WithOwnershipLabels sets the ownership labels of the parent object on the
+// target object, in addition to the owner reference. They allow tracking the
+// target objects that can't have an owner reference to the parent, like
+// cluster-scoped objects, see object.SetOwnershipLabels.
+func WithOwnershipLabels() OperandOption {
+	return func(o *Operand) {
+		o.ownershipLabels = true
+	}
+}
+
+// NewOperand creates a drift detecting Operand with the given name, client and
+// DriftDetector.
+func NewOperand(name string, c client.Client, detector operand.DriftDetector, opts ...OperandOption) *Operand {
+	o := &Operand{
+		name:            name,
+		client:          c,
+		detector:        detector,
+		requeueStrategy: operand.RequeueOnError,
+		fieldManager:    constant.LibraryName,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Name implements the Operand interface.
+func (o *Operand) Name() string { return o.name }
+
+// Requires implements the Operand interface.
+func (o *Operand) Requires() []string { return o.requires }
+
+// CleanupRequires implements the Operand interface.
+func (o *Operand) CleanupRequires() []string { return o.cleanupRequires }
+
+// RequeueStrategy implements the Operand interface.
+func (o *Operand) RequeueStrategy() operand.RequeueStrategy { return o.requeueStrategy }
+
+// ReadyCheck implements the Operand interface.
+func (o *Operand) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	if o.readyCheck == nil {
+		return true, nil
+	}
+	return o.readyCheck(ctx, obj)
+}
+
+// PostReady implements the Operand interface.
+func (o *Operand) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+// SupportsDryRun implements the operand.DryRunner interface. The target object
+// is written through dryrun.ClientFor.
+func (o *Operand) SupportsDryRun() bool { return true }
+
+// Ensure implements the Operand interface. It performs a dry-run apply of the
+// desired object and compares the result with the live object. The desired
+// object is applied only if a drift is found.
+func (o *Operand) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	desired, err := o.desired(ctx, obj, ownerRef)
+	if err != nil {
+		return nil, err
+	}
+	target := fmt.Sprintf("%s/%s", desired.GetKind(), desired.GetName())
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	if err := o.client.Get(ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s: %w", target, err)
+		}
+		// Not found, apply the desired object.
+		if err := o.apply(ctx, dryrun.ClientFor(ctx, o.client), desired); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", target, err)
+		}
+		return &Event{Object: obj, Target: target}, nil
+	}
+
+	// Dry-run the apply to get the object that would result from applying
+	// the desired object, including the defaults set by the API server.
+	dryRun := desired.DeepCopy()
+	if err := o.apply(ctx, o.client, dryRun, client.DryRunAll); err != nil {
+		return nil, fmt.Errorf("failed to dry-run apply %s: %w", target, err)
+	}
+
+	fields := Diff(live.Object, dryRun.Object, o.detector.IgnoreFields()...)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if err := o.apply(ctx, dryrun.ClientFor(ctx, o.client), desired); err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %w", target, err)
+	}
+	return &Event{Object: obj, Target: target, Fields: fields}, nil
+}
+
+// Delete implements the Operand interface. It deletes the desired object.
+func (o *Operand) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	desired, err := o.desired(ctx, obj, metav1.OwnerReference{})
+	if err != nil {
+		return nil, err
+	}
+	return nil, client.IgnoreNotFound(dryrun.ClientFor(ctx, o.client).Delete(ctx, desired))
+}
+
+// desired returns the desired object as unstructured, with the owner
+// reference set, if any.
+func (o *Operand) desired(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (*unstructured.Unstructured, error) {
+	d, err := o.detector.Desired(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get desired object: %w", err)
+	}
+
+	gvk, err := apiutil.GVKForObject(d, o.client.Scheme())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVK of the desired object: %w", err)
+	}
+
+	u, err := object.GetUnstructuredObject(o.client.Scheme(), d)
+	if err != nil {
+		return nil, err
+	}
+	u.SetGroupVersionKind(gvk)
+
+	if ownerRef.UID != "" && !hasOwnerReference(u.GetOwnerReferences(), ownerRef) {
+		u.SetOwnerReferences(append(u.GetOwnerReferences(), ownerRef))
+	}
+	if ownerRef.UID != "" && o.ownershipLabels {
+		if err := object.SetOwnershipLabels(u, object.OwnerFromReference(ownerRef, obj.GetNamespace())); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// apply applies the given object with server-side apply using the given
+// client, forcing the ownership of the fields.
+func (o *Operand) apply(ctx context.Context, c client.Client, obj *unstructured.Unstructured, opts ...client.PatchOption) error {
+	// The apply request must not contain the resource version and the
+	// managed fields.
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+	opts = append(opts, client.FieldOwner(o.fieldManager), client.ForceOwnership)
+	return c.Patch(ctx, obj, client.Apply, opts...)
+}
+
+// hasOwnerReference checks if the given owner reference is in the list.
+func hasOwnerReference(refs []metav1.OwnerReference, ref metav1.OwnerReference) bool {
+	for _, r := range refs {
+		if r.UID == ref.UID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package drift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+)
+
+// applyClient emulates server-side apply on top of the fake client, which
+// doesn't support apply patches. The applied object is merged into the live
+// object.
+type applyClient struct {
+	client.Client
+	applied int
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	po := &client.PatchOptions{}
+	po.ApplyOptions(opts)
+	dryRun := len(po.DryRun) > 0
+
+	u := obj.(*unstructured.Unstructured)
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(u.GroupVersionKind())
+	if err := c.Get(ctx, client.ObjectKeyFromObject(u), live); err != nil {
+		if !apierrors.IsNotFound(err) || dryRun {
+			return err
+		}
+		c.applied++
+		return c.Create(ctx, u)
+	}
+
+	merged := mergeMaps(live.Object, u.Object)
+	if dryRun {
+		u.Object = merged
+		return nil
+	}
+	c.applied++
+	live.Object = merged
+	if err := c.Update(ctx, live); err != nil {
+		return err
+	}
+	u.Object = live.Object
+	return nil
+}
+
+// mergeMaps returns a copy of dst with the fields of src merged into it.
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(dst)
+	for k, v := range src {
+		sm, sok := v.(map[string]interface{})
+		dm, dok := out[k].(map[string]interface{})
+		if sok && dok {
+			out[k] = mergeMaps(dm, sm)
+			continue
+		}
+		out[k] = runtime.DeepCopyJSONValue(v)
+	}
+	return out
+}
+
+// configMapDetector is a DriftDetector of a ConfigMap.
+type configMapDetector struct {
+	data   map[string]string
+	ignore []operand.FieldPath
+}
+
+func (d *configMapDetector) Desired(ctx context.Context, obj client.Object) (client.Object, error) {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Namespace:   obj.GetNamespace(),
+			Annotations: map[string]string{"managed": "true"},
+		},
+		Data: d.data,
+	}, nil
+}
+
+func (d *configMapDetector) IgnoreFields() []operand.FieldPath {
+	return d.ignore
+}
+
+func TestOperandEnsure(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "parent", UID: "parent-uid"}
+
+	liveConfigMap := func(data map[string]string, annotations map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "foo",
+				Namespace:       "default",
+				Annotations:     annotations,
+				OwnerReferences: []metav1.OwnerReference{ownerRef},
+			},
+			Data: data,
+		}
+	}
+
+	testcases := []struct {
+		name        string
+		existing    []client.Object
+		detector    *configMapDetector
+		wantApplied int
+		wantEvent   bool
+		wantFields  []string
+	}{
+		{
+			name:        "create",
+			detector:    &configMapDetector{data: map[string]string{"a": "1"}},
+			wantApplied: 1,
+			wantEvent:   true,
+		},
+		{
+			name: "in sync",
+			existing: []client.Object{
+				liveConfigMap(map[string]string{"a": "1"}, map[string]string{"managed": "true"}),
+			},
+			detector: &configMapDetector{data: map[string]string{"a": "1"}},
+		},
+		{
+			name: "drifted",
+			existing: []client.Object{
+				liveConfigMap(map[string]string{"a": "2"}, map[string]string{"managed": "true"}),
+			},
+			detector:    &configMapDetector{data: map[string]string{"a": "1"}},
+			wantApplied: 1,
+			wantEvent:   true,
+			wantFields:  []string{"data.a"},
+		},
+		{
+			name: "drift in ignored field",
+			existing: []client.Object{
+				liveConfigMap(map[string]string{"a": "1"}, map[string]string{"managed": "true", "other": "x"}),
+			},
+			detector: &configMapDetector{
+				data:   map[string]string{"a": "1"},
+				ignore: []operand.FieldPath{{"metadata", "annotations"}},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := &applyClient{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme.Scheme).
+					WithObjects(tc.existing...).
+					Build(),
+			}
+
+			op := NewOperand("configmap", cli, tc.detector)
+			event, err := op.Ensure(context.Background(), parent, ownerRef)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantApplied, cli.applied, "number of applies")
+
+			if !tc.wantEvent {
+				assert.Nil(t, event)
+			} else if assert.NotNil(t, event) {
+				e := event.(*Event)
+				assert.Equal(t, "ConfigMap/foo", e.Target)
+				assert.Equal(t, tc.wantFields, e.Fields)
+
+				// The event is recorded on the parent.
+				rec := record.NewFakeRecorder(1)
+				e.Record(rec)
+				assert.Contains(t, <-rec.Events, ReasonDriftCorrected)
+			}
+
+			// The live object is in the desired state with the owner
+			// reference.
+			got := &corev1.ConfigMap{}
+			assert.Nil(t, cli.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: "default"}, got))
+			assert.Equal(t, "1", got.Data["a"])
+			assert.Equal(t, []metav1.OwnerReference{ownerRef}, got.OwnerReferences)
+		})
+	}
+}
+
+func TestOperandDelete(t *testing.T) {
+	parent := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default"}}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}).
+		Build()
+
+	op := NewOperand("configmap", cli, &configMapDetector{})
+
+	// Delete twice, the second delete must not fail.
+	for i := 0; i < 2; i++ {
+		_, err := op.Delete(context.Background(), parent)
+		assert.Nil(t, err)
+	}
+
+	err := cli.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: "default"}, &corev1.ConfigMap{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -6,10 +6,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/ondat/operator-toolkit/client/dryrun"
 	eventv1 "github.com/ondat/operator-toolkit/event/v1"
 )
 
@@ -27,6 +30,14 @@ const (
 // ErrNotReady is returned by operand when the ready check fails.
 var ErrNotReady = errors.New("operand not ready")
 
+// ErrTimeout is returned when an operand execution doesn't finish within its
+// timeout.
+var ErrTimeout = errors.New("operand execution timed out")
+
+// ErrInFlight is returned when an operand isn't executed because its previous
+// execution for the same parent object, which timed out, is still running.
+var ErrInFlight = errors.New("previous operand execution still in progress")
+
 // Operand defines a single operation that's part of a composite operator. It
 // contains implementation details about how an action is performed, maybe for
 // creating a resource, and how to reverse/undo the action, maybe for cleanup
@@ -68,6 +79,59 @@ type Operand interface {
 	PostReady(context.Context, client.Object) error
 }
 
+// Timeouter is an optional interface that an operand can implement to limit
+// the duration of its execution. The context passed to the operand is
+// cancelled after the timeout. A zero timeout means no timeout.
+type Timeouter interface {
+	// Timeout returns the maximum duration of the operand execution.
+	Timeout() time.Duration
+}
+
+// Enabler is an optional interface that an operand can implement to be
+// enabled or disabled based on the parent object, usually a feature flag in
+// its spec. It's evaluated on every reconciliation. A disabled operand is
+// cleaned up and the operands that require it don't wait for it.
+type Enabler interface {
+	// Enabled returns true if the operand is enabled for the given parent
+	// object.
+	Enabled(context.Context, client.Object) bool
+}
+
+// KindManager is an optional interface that an operand can implement to
+// declare the kinds of the objects it manages. It's used to set up the watches
+// of the child objects of the parent objects.
+type KindManager interface {
+	// ManagedKinds returns the kinds of the objects managed by the operand.
+	ManagedKinds() []schema.GroupVersionKind
+}
+
+// DryRunner is an optional interface that an operand can implement to declare
+// that it supports the dry-run mode of the operator, usually by writing
+// through dryrun.ClientFor. In dry-run mode, the operands that don't support
+// it aren't executed since they would apply their changes.
+type DryRunner interface {
+	// SupportsDryRun returns true if the operand makes no change in
+	// dry-run mode.
+	SupportsDryRun() bool
+}
+
+// FieldPath is the path of a field in an object, e.g. ["spec", "replicas"].
+type FieldPath []string
+
+// DriftDetector is an optional interface that an operand can implement to
+// describe the desired state of its target object. It's used to detect a
+// drift between the desired and the live state of the target object.
+type DriftDetector interface {
+	// Desired returns the desired target object of the operand for the given
+	// parent object.
+	Desired(context.Context, client.Object) (client.Object, error)
+
+	// IgnoreFields returns the paths of the fields that are ignored when
+	// comparing the desired and the live object. These are usually the fields
+	// that are managed by other controllers.
+	IgnoreFields() []FieldPath
+}
+
 // OperandRunCall defines a function type used to define a function that
 // returns an operand execute call. This is used for passing the operand
 // execute function (Ensure or Delete) in a generic way.
@@ -85,7 +149,8 @@ const (
 // and the ReadyCheck of a given operand. The Ensure function ensures that the
 // desired change is applied to the world and ReadyCheck helps proceed only
 // when the desired state of the world is reached. This helps run dependent
-// operands only after a successful operand execution.
+// operands only after a successful operand execution. In dry-run mode, the
+// ReadyCheck and PostReady are skipped since no change is applied.
 func CallEnsure(op Operand) func(context.Context, client.Object, metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
 	return func(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
 		event, err := op.Ensure(ctx, obj, ownerRef)
@@ -93,6 +158,10 @@ func CallEnsure(op Operand) func(context.Context, client.Object, metav1.OwnerRef
 			return nil, err
 		}
 
+		if dryrun.IsDryRun(ctx) {
+			return event, nil
+		}
+
 		ready, readyErr := op.ReadyCheck(ctx, obj)
 		if readyErr != nil {
 			return nil, readyErr
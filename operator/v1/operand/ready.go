@@ -0,0 +1,107 @@
+package operand
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsResourceReady checks if the given live resource is ready. It supports
+// Deployments, StatefulSets, DaemonSets, Jobs, PersistentVolumeClaims and
+// Services. Other resources are always considered ready.
+func IsResourceReady(obj client.Object) (bool, error) {
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		return isDeploymentReady(o), nil
+	case *appsv1.StatefulSet:
+		return isStatefulSetReady(o), nil
+	case *appsv1.DaemonSet:
+		return isDaemonSetReady(o), nil
+	case *batchv1.Job:
+		return isJobReady(o)
+	case *corev1.PersistentVolumeClaim:
+		return o.Status.Phase == corev1.ClaimBound, nil
+	case *corev1.Service:
+		return isServiceReady(o), nil
+	default:
+		return true, nil
+	}
+}
+
+// desiredReplicas returns the number of replicas, defaulting to one when
+// unset.
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// isDeploymentReady checks if the latest generation of the deployment is
+// observed and all the replicas are updated and available.
+func isDeploymentReady(d *appsv1.Deployment) bool {
+	if d.Status.ObservedGeneration < d.Generation {
+		return false
+	}
+	replicas := desiredReplicas(d.Spec.Replicas)
+	return d.Status.UpdatedReplicas == replicas &&
+		d.Status.AvailableReplicas == replicas &&
+		d.Status.Replicas == replicas
+}
+
+// isStatefulSetReady checks if the latest generation of the statefulset is
+// observed and all the replicas are updated and ready.
+func isStatefulSetReady(s *appsv1.StatefulSet) bool {
+	if s.Status.ObservedGeneration < s.Generation {
+		return false
+	}
+	replicas := desiredReplicas(s.Spec.Replicas)
+	if s.Status.ReadyReplicas != replicas {
+		return false
+	}
+	// With OnDelete update strategy, the pods aren't updated by the
+	// controller.
+	if s.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+		return true
+	}
+	return s.Status.UpdatedReplicas == replicas && s.Status.CurrentRevision == s.Status.UpdateRevision
+}
+
+// isDaemonSetReady checks if the latest generation of the daemonset is
+// observed and the pods on all the scheduled nodes are updated and available.
+func isDaemonSetReady(d *appsv1.DaemonSet) bool {
+	if d.Status.ObservedGeneration < d.Generation {
+		return false
+	}
+	return d.Status.UpdatedNumberScheduled == d.Status.DesiredNumberScheduled &&
+		d.Status.NumberAvailable == d.Status.DesiredNumberScheduled
+}
+
+// isJobReady checks if the job has completed. An error is returned if the job
+// has failed.
+func isJobReady(j *batchv1.Job) (bool, error) {
+	for _, c := range j.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return false, fmt.Errorf("job %q failed: %s", j.GetName(), c.Message)
+		}
+	}
+	return false, nil
+}
+
+// isServiceReady checks if a load balancer service has an ingress point.
+// Other types of services are ready once created.
+func isServiceReady(s *corev1.Service) bool {
+	if s.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return true
+	}
+	return len(s.Status.LoadBalancer.Ingress) > 0
+}
@@ -0,0 +1,157 @@
+package operand
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestIsResourceReady(t *testing.T) {
+	three := int32(3)
+
+	testcases := []struct {
+		name      string
+		obj       client.Object
+		wantReady bool
+		wantErr   bool
+	}{
+		{
+			name: "deployment with old generation",
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &three},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+			},
+		},
+		{
+			name: "deployment ready",
+			obj: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: &three},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3},
+			},
+			wantReady: true,
+		},
+		{
+			name: "deployment with default replicas",
+			obj: &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+			wantReady: true,
+		},
+		{
+			name: "statefulset rolling out",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{Replicas: &three},
+				Status: appsv1.StatefulSetStatus{
+					ReadyReplicas: 3, UpdatedReplicas: 1,
+					CurrentRevision: "a", UpdateRevision: "b",
+				},
+			},
+		},
+		{
+			name: "statefulset ready",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{Replicas: &three},
+				Status: appsv1.StatefulSetStatus{
+					ReadyReplicas: 3, UpdatedReplicas: 3,
+					CurrentRevision: "b", UpdateRevision: "b",
+				},
+			},
+			wantReady: true,
+		},
+		{
+			name: "statefulset on delete",
+			obj: &appsv1.StatefulSet{
+				Spec: appsv1.StatefulSetSpec{
+					Replicas:       &three,
+					UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.OnDeleteStatefulSetStrategyType},
+				},
+				Status: appsv1.StatefulSetStatus{ReadyReplicas: 3},
+			},
+			wantReady: true,
+		},
+		{
+			name: "daemonset not available",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			},
+		},
+		{
+			name: "daemonset ready",
+			obj: &appsv1.DaemonSet{
+				Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			},
+			wantReady: true,
+		},
+		{
+			name: "job running",
+			obj:  &batchv1.Job{},
+		},
+		{
+			name: "job complete",
+			obj: &batchv1.Job{
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+				}},
+			},
+			wantReady: true,
+		},
+		{
+			name: "job failed",
+			obj: &batchv1.Job{
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "backoff limit"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pvc pending",
+			obj:  &corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending}},
+		},
+		{
+			name:      "pvc bound",
+			obj:       &corev1.PersistentVolumeClaim{Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound}},
+			wantReady: true,
+		},
+		{
+			name:      "cluster ip service",
+			obj:       &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}},
+			wantReady: true,
+		},
+		{
+			name: "load balancer service without ingress",
+			obj:  &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}},
+		},
+		{
+			name: "load balancer service with ingress",
+			obj: &corev1.Service{
+				Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+				Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+					Ingress: []corev1.LoadBalancerIngress{{IP: "10.0.0.1"}},
+				}},
+			},
+			wantReady: true,
+		},
+		{
+			name:      "other resource",
+			obj:       &corev1.ConfigMap{},
+			wantReady: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ready, err := IsResourceReady(tc.obj)
+			assert.Equal(t, tc.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tc.wantReady, ready)
+		})
+	}
+}
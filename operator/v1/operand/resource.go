@@ -0,0 +1,366 @@
+package operand
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/ondat/operator-toolkit/client/dryrun"
+	eventv1 "github.com/ondat/operator-toolkit/event/v1"
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// ResourceBuilder returns the desired state of a resource for the given parent
+// object.
+type ResourceBuilder[T client.Object] func(ctx context.Context, parent client.Object) (T, error)
+
+// ResourceOperand is a generic operand that manages a single resource of type
+// T. The desired state of the resource is built by a ResourceBuilder. The
+// resource is created if it doesn't exist and is updated only when the live
+// resource doesn't contain the desired state. Fields set by the API server or
+// other controllers that aren't part of the desired state are preserved.
+type ResourceOperand[T client.Object] struct {
+	name            string
+	client          client.Client
+	build           ResourceBuilder[T]
+	requires        []string
+	cleanupRequires []string
+	requeueStrategy RequeueStrategy
+	readyCheck      func(context.Context, client.Object) (bool, error)
+	ownerAnnotation bool
+	ownershipLabels bool
+}
+
+// ResourceOption is used to configure ResourceOperand.
+type ResourceOption func(*resourceConfig)
+
+// resourceConfig is the type independent configuration of a ResourceOperand.
+type resourceConfig struct {
+	requires        []string
+	cleanupRequires []string
+	requeueStrategy RequeueStrategy
+	readyCheck      func(context.Context, client.Object) (bool, error)
+	ownerAnnotation bool
+	ownershipLabels bool
+}
+
+// WithResourceRequires sets the operands that the resource operand requires
+// during ensure.
+func WithResourceRequires(requires ...string) ResourceOption {
+	return func(c *resourceConfig) {
+		c.requires = requires
+	}
+}
+
+// WithResourceCleanupRequires sets the operands that the resource operand
+// requires during cleanup.
+func WithResourceCleanupRequires(requires ...string) ResourceOption {
+	return func(c *resourceConfig) {
+		c.cleanupRequires = requires
+	}
+}
+
+// WithResourceRequeueStrategy sets the requeue strategy of the resource
+// operand.
+func WithResourceRequeueStrategy(strategy RequeueStrategy) ResourceOption {
+	return func(c *resourceConfig) {
+		c.requeueStrategy = strategy
+	}
+}
+
+// WithResourceReadyCheck sets a custom ready check of the live resource. By
+// default, IsResourceReady is used.
+func WithResourceReadyCheck(f func(ctx context.Context, live client.Object) (bool, error)) ResourceOption {
+	return func(c *resourceConfig) {
+		c.readyCheck = f
+	}
+}
+
+// WithResourceOwnerAnnotation sets the owner annotations of the resource
+// instead of an owner reference to the parent object. It's used for resources
+// that can't have an owner reference to the parent object, like cluster-scoped
+// resources of a namespaced parent and resources in another namespace. These
+// resources aren't garbage collected and must be deleted on cleanup.
+func WithResourceOwnerAnnotation() ResourceOption {
+	return func(c *resourceConfig) {
+		c.ownerAnnotation = true
+	}
+}
+
+// WithResourceOwnershipLabels sets the ownership labels of the parent object
+// on the resource, in addition to the owner reference or the owner
+// annotations. They allow tracking the resources that can't have an owner
+// reference to the parent, see object.SetOwnershipLabels.
+func WithResourceOwnershipLabels() ResourceOption {
+	return func(c *resourceConfig) {
+		c.ownershipLabels = true
+	}
+}
+
+var _ Operand = &ResourceOperand[client.Object]{}
+var _ KindManager = &ResourceOperand[client.Object]{}
+
+// NewResourceOperand creates a ResourceOperand with the given name, client and
+// desired state builder.
+func NewResourceOperand[T client.Object](name string, c client.Client, build ResourceBuilder[T], opts ...ResourceOption) *ResourceOperand[T] {
+	cfg := &resourceConfig{
+		requeueStrategy: RequeueOnError,
+		readyCheck: func(ctx context.Context, live client.Object) (bool, error) {
+			return IsResourceReady(live)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return &ResourceOperand[T]{
+		name:            name,
+		client:          c,
+		build:           build,
+		requires:        cfg.requires,
+		cleanupRequires: cfg.cleanupRequires,
+		requeueStrategy: cfg.requeueStrategy,
+		readyCheck:      cfg.readyCheck,
+		ownerAnnotation: cfg.ownerAnnotation,
+		ownershipLabels: cfg.ownershipLabels,
+	}
+}
+
+// Name implements the Operand interface.
+func (r *ResourceOperand[T]) Name() string { return r.name }
+
+// Requires implements the Operand interface.
+func (r *ResourceOperand[T]) Requires() []string { return r.requires }
+
+// CleanupRequires implements the Operand interface.
+func (r *ResourceOperand[T]) CleanupRequires() []string { return r.cleanupRequires }
+
+// RequeueStrategy implements the Operand interface.
+func (r *ResourceOperand[T]) RequeueStrategy() RequeueStrategy { return r.requeueStrategy }
+
+// PostReady implements the Operand interface.
+func (r *ResourceOperand[T]) PostReady(ctx context.Context, obj client.Object) error { return nil }
+
+// Ensure implements the Operand interface. It creates the resource if it
+// doesn't exist, or updates it if the live resource isn't in the desired
+// state. The owner reference, if set, is added to the resource.
+func (r *ResourceOperand[T]) Ensure(ctx context.Context, obj client.Object, ownerRef metav1.OwnerReference) (eventv1.ReconcilerEvent, error) {
+	desired, err := r.build(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build desired resource: %w", err)
+	}
+	if ownerRef.UID != "" {
+		if r.ownerAnnotation {
+			ownerKind := schema.FromAPIVersionAndKind(ownerRef.APIVersion, ownerRef.Kind).GroupKind()
+			object.SetOwnerAnnotations(desired, ownerKind, types.NamespacedName{Namespace: obj.GetNamespace(), Name: ownerRef.Name})
+		} else if !containsOwnerReference(desired.GetOwnerReferences(), ownerRef) {
+			desired.SetOwnerReferences(append(desired.GetOwnerReferences(), ownerRef))
+		}
+		if r.ownershipLabels {
+			if err := object.SetOwnershipLabels(desired, object.OwnerFromReference(ownerRef, obj.GetNamespace())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	gvk, err := apiutil.GVKForObject(desired, r.client.Scheme())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVK of the resource: %w", err)
+	}
+	event := &ResourceEvent{Object: obj, Kind: gvk.Kind, Name: desired.GetName()}
+
+	live := desired.DeepCopyObject().(T)
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get %s %q: %w", gvk.Kind, desired.GetName(), err)
+		}
+		if err := dryrun.ClientFor(ctx, r.client).Create(ctx, desired); err != nil {
+			return nil, fmt.Errorf("failed to create %s %q: %w", gvk.Kind, desired.GetName(), err)
+		}
+		event.Action = ResourceCreated
+		return event, nil
+	}
+
+	desiredContent, err := desiredContent(desired)
+	if err != nil {
+		return nil, err
+	}
+	liveContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert live resource to unstructured: %w", err)
+	}
+
+	// No update is needed if all the desired fields are set in the live
+	// resource.
+	if equality.Semantic.DeepDerivative(desiredContent, liveContent) {
+		return nil, nil
+	}
+
+	updated := &unstructured.Unstructured{Object: mergeContent(liveContent, desiredContent)}
+	updated.SetGroupVersionKind(gvk)
+	if err := dryrun.ClientFor(ctx, r.client).Update(ctx, updated); err != nil {
+		return nil, fmt.Errorf("failed to update %s %q: %w", gvk.Kind, desired.GetName(), err)
+	}
+	event.Action = ResourceUpdated
+	return event, nil
+}
+
+// Delete implements the Operand interface. It deletes the resource if it
+// exists.
+func (r *ResourceOperand[T]) Delete(ctx context.Context, obj client.Object) (eventv1.ReconcilerEvent, error) {
+	desired, err := r.build(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build desired resource: %w", err)
+	}
+
+	gvk, err := apiutil.GVKForObject(desired, r.client.Scheme())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GVK of the resource: %w", err)
+	}
+
+	if err := dryrun.ClientFor(ctx, r.client).Delete(ctx, desired); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to delete %s %q: %w", gvk.Kind, desired.GetName(), err)
+	}
+	return &ResourceEvent{Object: obj, Action: ResourceDeleted, Kind: gvk.Kind, Name: desired.GetName()}, nil
+}
+
+// ManagedKinds implements the KindManager interface. It returns the kind of
+// T, if T is a concrete type registered in the scheme of the client.
+func (r *ResourceOperand[T]) ManagedKinds() []schema.GroupVersionKind {
+	obj, err := object.New[T]()
+	if err != nil {
+		return nil
+	}
+	gvk, err := apiutil.GVKForObject(obj, r.client.Scheme())
+	if err != nil {
+		return nil
+	}
+	return []schema.GroupVersionKind{gvk}
+}
+
+// SupportsDryRun implements the DryRunner interface. The resource is written
+// through dryrun.ClientFor.
+func (r *ResourceOperand[T]) SupportsDryRun() bool { return true }
+
+// ReadyCheck implements the Operand interface. It fetches the live resource
+// and checks if it's ready.
+func (r *ResourceOperand[T]) ReadyCheck(ctx context.Context, obj client.Object) (bool, error) {
+	desired, err := r.build(ctx, obj)
+	if err != nil {
+		return false, fmt.Errorf("failed to build desired resource: %w", err)
+	}
+
+	live := desired.DeepCopyObject().(T)
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return r.readyCheck(ctx, live)
+}
+
+// desiredContent returns the content of the desired resource that's compared
+// with the live resource. The status, the fields set by the API server and
+// the unset fields are removed.
+func desiredContent(desired client.Object) (map[string]interface{}, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert desired resource to unstructured: %w", err)
+	}
+	delete(content, "status")
+	for _, field := range []string{"creationTimestamp", "generation", "managedFields", "resourceVersion", "selfLink", "uid"} {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+	pruneNil(content)
+	return content, nil
+}
+
+// pruneNil recursively removes the nil values from the given map. Nil values
+// are unset fields of a typed object.
+func pruneNil(m map[string]interface{}) {
+	for k, v := range m {
+		switch val := v.(type) {
+		case nil:
+			delete(m, k)
+		case map[string]interface{}:
+			pruneNil(val)
+		case []interface{}:
+			for _, item := range val {
+				if im, ok := item.(map[string]interface{}); ok {
+					pruneNil(im)
+				}
+			}
+		}
+	}
+}
+
+// mergeContent returns a copy of the live content with the desired content
+// merged into it. Maps are merged recursively, other values are replaced.
+func mergeContent(live, desired map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(live)
+	for k, v := range desired {
+		dm, dok := v.(map[string]interface{})
+		lm, lok := out[k].(map[string]interface{})
+		if dok && lok {
+			out[k] = mergeContent(lm, dm)
+			continue
+		}
+		out[k] = runtime.DeepCopyJSONValue(v)
+	}
+	return out
+}
+
+// containsOwnerReference checks if the given owner reference is in the list.
+func containsOwnerReference(refs []metav1.OwnerReference, ref metav1.OwnerReference) bool {
+	for _, r := range refs {
+		if r.UID == ref.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourceAction is an action performed on a resource by a ResourceOperand.
+type ResourceAction string
+
+const (
+	ResourceCreated ResourceAction = "Created"
+	ResourceUpdated ResourceAction = "Updated"
+	ResourceDeleted ResourceAction = "Deleted"
+)
+
+// ResourceEvent is a ReconcilerEvent recorded on the parent object when a
+// ResourceOperand changes a resource.
+type ResourceEvent struct {
+	// Object is the parent object.
+	Object client.Object
+	// Action is the action performed on the resource.
+	Action ResourceAction
+	// Kind is the kind of the resource.
+	Kind string
+	// Name is the name of the resource.
+	Name string
+}
+
+var _ eventv1.ReconcilerEvent = &ResourceEvent{}
+
+// Record implements eventv1.ReconcilerEvent.
+func (e *ResourceEvent) Record(recorder record.EventRecorder) {
+	recorder.Event(e.Object,
+		eventv1.K8sEventTypeNormal,
+		e.Kind+string(e.Action),
+		fmt.Sprintf("%s %s %s", e.Action, e.Kind, e.Name),
+	)
+}
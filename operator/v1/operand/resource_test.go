@@ -0,0 +1,269 @@
+package operand
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/object"
+)
+
+func TestResourceOperandEnsure(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "parent", UID: "parent-uid"}
+
+	build := func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: parent.GetNamespace(),
+				Labels:    map[string]string{"app": "foo"},
+			},
+			Data: map[string]string{"a": "1"},
+		}, nil
+	}
+
+	testcases := []struct {
+		name       string
+		existing   []client.Object
+		wantAction ResourceAction
+		wantLabels map[string]string
+	}{
+		{
+			name:       "create",
+			wantAction: ResourceCreated,
+			wantLabels: map[string]string{"app": "foo"},
+		},
+		{
+			name: "in desired state",
+			existing: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "foo",
+						Namespace:       "default",
+						Labels:          map[string]string{"app": "foo", "other": "bar"},
+						OwnerReferences: []metav1.OwnerReference{ownerRef},
+					},
+					Data: map[string]string{"a": "1"},
+				},
+			},
+			wantLabels: map[string]string{"app": "foo", "other": "bar"},
+		},
+		{
+			name: "update",
+			existing: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "default",
+						Labels:    map[string]string{"other": "bar"},
+					},
+					Data: map[string]string{"a": "2"},
+				},
+			},
+			wantAction: ResourceUpdated,
+			// Fields that aren't part of the desired state are preserved.
+			wantLabels: map[string]string{"app": "foo", "other": "bar"},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(tc.existing...).
+				Build()
+
+			op := NewResourceOperand("configmap", cli, build, WithResourceRequires("other"))
+			assert.Equal(t, []string{"other"}, op.Requires())
+
+			event, err := op.Ensure(context.Background(), parent, ownerRef)
+			assert.Nil(t, err)
+			if tc.wantAction == "" {
+				assert.Nil(t, event)
+			} else if assert.NotNil(t, event) {
+				e := event.(*ResourceEvent)
+				assert.Equal(t, tc.wantAction, e.Action)
+				assert.Equal(t, "ConfigMap", e.Kind)
+				assert.Equal(t, "foo", e.Name)
+
+				rec := record.NewFakeRecorder(1)
+				e.Record(rec)
+				assert.Equal(t, "Normal ConfigMap"+string(tc.wantAction)+" "+string(tc.wantAction)+" ConfigMap foo", <-rec.Events)
+			}
+
+			got := &corev1.ConfigMap{}
+			assert.Nil(t, cli.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: "default"}, got))
+			assert.Equal(t, "1", got.Data["a"])
+			assert.Equal(t, tc.wantLabels, got.Labels)
+			assert.Equal(t, []metav1.OwnerReference{ownerRef}, got.OwnerReferences)
+
+			// A second ensure doesn't change anything.
+			event, err = op.Ensure(context.Background(), parent, ownerRef)
+			assert.Nil(t, err)
+			assert.Nil(t, event)
+		})
+	}
+}
+
+func TestResourceOperandDelete(t *testing.T) {
+	parent := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default"}}
+	cli := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}).
+		Build()
+
+	op := NewResourceOperand("configmap", cli, func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: parent.GetNamespace()}}, nil
+	})
+
+	event, err := op.Delete(context.Background(), parent)
+	assert.Nil(t, err)
+	if assert.NotNil(t, event) {
+		assert.Equal(t, ResourceDeleted, event.(*ResourceEvent).Action)
+	}
+
+	err = cli.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: "default"}, &corev1.ConfigMap{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Deleting a resource that doesn't exist is a no-op.
+	event, err = op.Delete(context.Background(), parent)
+	assert.Nil(t, err)
+	assert.Nil(t, event)
+}
+
+func TestResourceOperandOwnerAnnotation(t *testing.T) {
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "parent", UID: "parent-uid"}
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	op := NewResourceOperand("namespace", cli, func(ctx context.Context, parent client.Object) (*corev1.Namespace, error) {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}, nil
+	}, WithResourceOwnerAnnotation(), WithResourceOwnershipLabels())
+
+	_, err := op.Ensure(context.Background(), parent, ownerRef)
+	assert.Nil(t, err)
+
+	got := &corev1.Namespace{}
+	assert.Nil(t, cli.Get(context.Background(), client.ObjectKey{Name: "foo"}, got))
+	assert.Empty(t, got.GetOwnerReferences())
+	kind, owner, ok := object.OwnerFromAnnotations(got)
+	assert.True(t, ok)
+	assert.Equal(t, schema.GroupKind{Kind: "Pod"}, kind)
+	assert.Equal(t, types.NamespacedName{Namespace: "default", Name: "parent"}, owner)
+
+	labelOwner, ok := object.OwnerFromLabels(got)
+	assert.True(t, ok)
+	assert.Equal(t, object.OwnerFromReference(ownerRef, "default"), labelOwner)
+}
+
+func TestResourceOperandOwnershipLabelsLongName(t *testing.T) {
+	name := strings.Repeat("a", 253)
+	parent := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: "parent-uid"},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: name, UID: "parent-uid"}
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	op := NewResourceOperand("configmap", cli, func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}, nil
+	}, WithResourceOwnershipLabels())
+
+	_, err := op.Ensure(context.Background(), parent, ownerRef)
+	assert.Nil(t, err)
+
+	got := &corev1.ConfigMap{}
+	assert.Nil(t, cli.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: "default"}, got))
+	owner, ok := object.OwnerFromLabels(got)
+	assert.True(t, ok)
+	assert.Equal(t, name, owner.Name)
+}
+
+func TestResourceOperandManagedKinds(t *testing.T) {
+	cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	cm := NewResourceOperand("configmap", cli, func(ctx context.Context, parent client.Object) (*corev1.ConfigMap, error) {
+		return &corev1.ConfigMap{}, nil
+	})
+	assert.Equal(t, []schema.GroupVersionKind{corev1.SchemeGroupVersion.WithKind("ConfigMap")}, cm.ManagedKinds())
+
+	// The kind of a resource of an interface type isn't known.
+	obj := NewResourceOperand("object", cli, func(ctx context.Context, parent client.Object) (client.Object, error) {
+		return &corev1.ConfigMap{}, nil
+	})
+	assert.Empty(t, obj.ManagedKinds())
+}
+
+func TestResourceOperandReadyCheck(t *testing.T) {
+	parent := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default"}}
+	replicas := int32(2)
+	build := func(ctx context.Context, parent client.Object) (*appsv1.Deployment, error) {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: parent.GetNamespace()},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}, nil
+	}
+
+	testcases := []struct {
+		name      string
+		existing  []client.Object
+		wantReady bool
+	}{
+		{
+			name: "not found",
+		},
+		{
+			name: "not ready",
+			existing: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+					Status:     appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+				},
+			},
+		},
+		{
+			name: "ready",
+			existing: []client.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+					Status:     appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+				},
+			},
+			wantReady: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(tc.existing...).
+				Build()
+
+			op := NewResourceOperand("deployment", cli, build)
+			ready, err := op.ReadyCheck(context.Background(), parent)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantReady, ready)
+		})
+	}
+}
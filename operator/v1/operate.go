@@ -8,6 +8,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/ondat/operator-toolkit/constant"
+	"github.com/ondat/operator-toolkit/operator/v1/executor"
 )
 
 // Name of the instrumentation.
@@ -21,12 +22,21 @@ type Operator interface {
 	IsSuspended(context.Context, client.Object) bool
 
 	// Ensure runs all the operands' Ensure method in order defined by their
-	// dependencies.
-	Ensure(context.Context, client.Object, metav1.OwnerReference) (result ctrl.Result, err error)
+	// dependencies. The returned report contains the state of each of the
+	// operands after the execution.
+	Ensure(context.Context, client.Object, metav1.OwnerReference) (result ctrl.Result, report *executor.ExecutionReport, err error)
 
 	// Cleanup runs all the operands' Delete method in reverse order defined by
-	// their dependencies.
-	Cleanup(context.Context, client.Object) (result ctrl.Result, err error)
+	// their dependencies. The returned report contains the state of each of
+	// the operands after the execution.
+	Cleanup(context.Context, client.Object) (result ctrl.Result, report *executor.ExecutionReport, err error)
+}
+
+// OperandStatusSetter is implemented by parent objects that report the
+// status of the operands in their status, usually in status.operands.
+type OperandStatusSetter interface {
+	// SetOperandStatuses sets the statuses of the operands in the object.
+	SetOperandStatuses([]executor.OperandStatus)
 }
 
 // defaultIsSuspended always returns false.
@@ -1,7 +1,9 @@
 package dag
 
 import (
-	"github.com/goombaio/dag"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/ondat/operator-toolkit/operator/v1/operand"
 	"github.com/ondat/operator-toolkit/operator/v1/playbook/order"
@@ -11,156 +13,217 @@ import (
 // dependencies. This is used to resolve the dependencies of the operands on
 // each other and find an optimal execution path.
 type OperandDAG struct {
-	*dag.DAG
+	// operands are the vertices of the graph by name.
+	operands map[string]operand.Operand
+	// successors are the operands that require an operand.
+	successors map[string][]string
+	// predecessors are the operands that an operand requires.
+	predecessors map[string][]string
 }
 
+// MissingDependencyError is returned when an operand requires an operand that
+// doesn't exist.
+type MissingDependencyError struct {
+	// Operand is the name of the operand with the missing dependency.
+	Operand string
+	// Dependency is the name of the required operand that doesn't exist.
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	if e.Operand == "" {
+		return fmt.Sprintf("requirements found for unknown operand %q", e.Dependency)
+	}
+	return fmt.Sprintf("operand %q requires unknown operand %q", e.Operand, e.Dependency)
+}
+
+// DuplicateOperandError is returned when multiple operands have the same
+// name.
+type DuplicateOperandError struct {
+	// Name is the duplicate operand name.
+	Name string
+}
+
+func (e *DuplicateOperandError) Error() string {
+	return fmt.Sprintf("duplicate operand %q", e.Name)
+}
+
+// CycleError is returned when the operand dependencies form a cycle.
+type CycleError struct {
+	// Path is the path of the cycle, starting and ending with the same
+	// operand. Each operand in the path is required by the next one.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("operand dependency cycle found: %s", strings.Join(e.Path, " -> "))
+}
+
+// NewOperandDAG creates an OperandDAG of the given operands with edges from
+// the required operands to the operands requiring them.
 func NewOperandDAG(operands []operand.Operand, requiredOperands map[string][]string) (*OperandDAG, error) {
-	od := &OperandDAG{dag.NewDAG()}
+	od := &OperandDAG{
+		operands:     make(map[string]operand.Operand, len(operands)),
+		successors:   make(map[string][]string, len(operands)),
+		predecessors: make(map[string][]string, len(operands)),
+	}
 
 	// Create vertices for all the operands.
 	for _, op := range operands {
-		v := dag.NewVertex(op.Name(), op)
-		if err := od.AddVertex(v); err != nil {
-			return nil, err
+		if _, exists := od.operands[op.Name()]; exists {
+			return nil, &DuplicateOperandError{Name: op.Name()}
 		}
+		od.operands[op.Name()] = op
 	}
 
-	// Create edges between the vertices based on the operand's depends on
-	// property.
-	for opName, required := range requiredOperands {
-		headVertex, err := od.GetVertex(opName)
-		if err != nil {
-			return nil, err
+	// Create edges between the vertices based on the operand's requirements.
+	// Iterate in sorted order for deterministic errors.
+	for _, opName := range sortedKeys(requiredOperands) {
+		if _, exists := od.operands[opName]; !exists {
+			return nil, &MissingDependencyError{Dependency: opName}
 		}
-
-		// Connect the operand to all the vertices it depends on.
-		for _, dep := range required {
-			tailVertex, err := od.GetVertex(dep)
-			if err != nil {
-				return nil, err
+		for _, dep := range requiredOperands[opName] {
+			if _, exists := od.operands[dep]; !exists {
+				return nil, &MissingDependencyError{Operand: opName, Dependency: dep}
 			}
-			if err := od.AddEdge(tailVertex, headVertex); err != nil {
-				return nil, err
+			if contains(od.predecessors[opName], dep) {
+				continue
 			}
+			od.predecessors[opName] = append(od.predecessors[opName], dep)
+			od.successors[dep] = append(od.successors[dep], opName)
 		}
 	}
 
+	for name := range od.operands {
+		sort.Strings(od.predecessors[name])
+		sort.Strings(od.successors[name])
+	}
+
 	return od, nil
 }
 
-func (od *OperandDAG) Order() (order.OperandOrder, error) {
-	soln, steps, err := od.solve()
-	if err != nil {
-		return nil, err
-	}
+// Len returns the number of operands in the graph.
+func (od *OperandDAG) Len() int {
+	return len(od.operands)
+}
 
-	result := make([][]operand.Operand, steps)
+// Operand returns the operand with the given name, or nil if not found.
+func (od *OperandDAG) Operand(name string) operand.Operand {
+	return od.operands[name]
+}
 
-	for name, step := range soln {
-		v, verr := od.GetVertex(name)
-		if verr != nil {
-			return result, verr
-		}
-		result[step] = append(result[step], v.Value.(operand.Operand))
-	}
+// Predecessors returns the sorted names of the operands that the given
+// operand requires.
+func (od *OperandDAG) Predecessors(name string) []string {
+	return od.predecessors[name]
+}
 
-	return result, nil
+// Successors returns the sorted names of the operands that require the given
+// operand.
+func (od *OperandDAG) Successors(name string) []string {
+	return od.successors[name]
 }
 
-// Solve solves the graph traversal in DAG with steps. Returns a map containing
-// vertex name with step number and total number of steps in the solution.
-func (od *OperandDAG) solve() (map[string]int, int, error) {
-	order := map[string]int{}
-	// Start from root.
-	roots := od.SourceVertices()
-
-	// Init order step and roots.
-	step := 0
-	newRoots := roots
-	var err error
-	for len(newRoots) > 0 {
-		newRoots, err = od.solveStep(step, newRoots, order)
-		if err != nil {
-			return nil, step, err
+// Order returns the execution order of the operands using Kahn's algorithm.
+// Each step contains the operands whose requirements are all in the previous
+// steps. The operands in a step are sorted by name. A CycleError is returned
+// if the dependencies form a cycle.
+func (od *OperandDAG) Order() (order.OperandOrder, error) {
+	inDegree := make(map[string]int, len(od.operands))
+	current := []string{}
+	for name := range od.operands {
+		inDegree[name] = len(od.predecessors[name])
+		if inDegree[name] == 0 {
+			current = append(current, name)
 		}
-		step++
 	}
-
-	return order, step, nil
-}
-
-// solveStep takes a step number, current roots and an order, and returns new
-// current roots and updates the order.
-func (od *OperandDAG) solveStep(step int, currentRoots []*dag.Vertex, order map[string]int) ([]*dag.Vertex, error) {
-	newRoots := []*dag.Vertex{}
-
-	for _, c := range currentRoots {
-		// Check if the current root exists in the order.
-		if _, exists := order[c.ID]; !exists {
-			// Check if the predecessors exists in the order. If not, skip,
-			// else, add to order.
-			pp, perr := od.Predecessors(c)
-			if perr != nil {
-				return nil, perr
-			}
-
-			if len(pp) == 0 {
-				// If no predecessor, add to order, it's the root.
-				order[c.ID] = step
-				var serr error
-				newRoots, serr = od.addSuccessorsToNewRoots(c, newRoots)
-				if serr != nil {
-					return nil, serr
-				}
-				continue
-			}
-
-			satisfied := true
-			for _, p := range pp {
-				if _, exists := order[p.ID]; !exists {
-					satisfied = false
+	sort.Strings(current)
+
+	result := order.OperandOrder{}
+	visited := 0
+	for len(current) > 0 {
+		step := make([]operand.Operand, 0, len(current))
+		next := []string{}
+		for _, name := range current {
+			step = append(step, od.operands[name])
+			for _, succ := range od.successors[name] {
+				inDegree[succ]--
+				if inDegree[succ] == 0 {
+					next = append(next, succ)
 				}
 			}
-
-			// Satisfied, then add to order.
-			if satisfied {
-				order[c.ID] = step
-			}
 		}
+		visited += len(current)
+		result = append(result, step)
+		sort.Strings(next)
+		current = next
+	}
 
-		// Get successors and add to new roots.
-		var serr error
-		newRoots, serr = od.addSuccessorsToNewRoots(c, newRoots)
-		if serr != nil {
-			return nil, serr
-		}
+	if visited != len(od.operands) {
+		return nil, &CycleError{Path: od.findCycle(inDegree)}
 	}
 
-	return newRoots, nil
+	return result, nil
 }
 
-// addSuccessorsToNewRoots takes a vertex, fetches its successors and adds the
-// successors to the newRoots list. This is used to create a list of all the
-// adjacent vertices at the same level in the graph.
-func (od *OperandDAG) addSuccessorsToNewRoots(v *dag.Vertex, newRoots []*dag.Vertex) ([]*dag.Vertex, error) {
-	ss, serr := od.Successors(v)
-	if serr != nil {
-		return nil, serr
+// findCycle returns a cycle path among the operands that couldn't be
+// ordered, the ones with a non-zero in-degree.
+func (od *OperandDAG) findCycle(inDegree map[string]int) []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := map[string]int{}
+	stack := []string{}
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = inProgress
+		stack = append(stack, name)
+		for _, succ := range od.successors[name] {
+			switch state[succ] {
+			case inProgress:
+				// Found a cycle, extract it from the stack.
+				for i, n := range stack {
+					if n == succ {
+						return append(append([]string{}, stack[i:]...), succ)
+					}
+				}
+			case unvisited:
+				if cycle := visit(succ); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil
 	}
 
-	// Add to root if not exists.
-	for _, s := range ss {
-		if !od.vertexExists(newRoots, s) {
-			newRoots = append(newRoots, s)
+	for _, name := range sortedKeys(inDegree) {
+		if inDegree[name] > 0 && state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
 		}
 	}
+	return nil
+}
 
-	return newRoots, nil
+// sortedKeys returns the sorted keys of a map.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func (od *OperandDAG) vertexExists(vs []*dag.Vertex, target *dag.Vertex) bool {
-	for _, v := range vs {
-		if v.ID == target.ID {
+func contains(slice []string, s string) bool {
+	for _, element := range slice {
+		if element == s {
 			return true
 		}
 	}
@@ -1,6 +1,9 @@
 package dag
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -92,3 +95,163 @@ func TestDAG(t *testing.T) {
 		t.Errorf("unexpected reverse results:\n\t(WNT) %q\n\t(GOT) %q", expectedReverseResult, reverseOrder)
 	}
 }
+
+// namedOperand is an operand with only a name, used where the graph
+// construction doesn't call the operand.
+type namedOperand struct {
+	operand.Operand
+	name string
+}
+
+func (o namedOperand) Name() string { return o.name }
+
+func namedOperands(names ...string) []operand.Operand {
+	ops := []operand.Operand{}
+	for _, name := range names {
+		ops = append(ops, namedOperand{name: name})
+	}
+	return ops
+}
+
+func TestDAGErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		operands []string
+		required map[string][]string
+		wantErr  string
+	}{
+		{
+			name:     "missing dependency",
+			operands: []string{"A", "B"},
+			required: map[string][]string{"A": {}, "B": {"A", "X"}},
+			wantErr:  `operand "B" requires unknown operand "X"`,
+		},
+		{
+			name:     "requirements of unknown operand",
+			operands: []string{"A"},
+			required: map[string][]string{"A": {}, "Z": {"A"}},
+			wantErr:  `requirements found for unknown operand "Z"`,
+		},
+		{
+			name:     "duplicate operand",
+			operands: []string{"A", "B", "A"},
+			required: map[string][]string{},
+			wantErr:  `duplicate operand "A"`,
+		},
+		{
+			name:     "self cycle",
+			operands: []string{"A"},
+			required: map[string][]string{"A": {"A"}},
+			wantErr:  "operand dependency cycle found: A -> A",
+		},
+		{
+			name:     "cycle",
+			operands: []string{"A", "B", "C", "D", "E"},
+			required: map[string][]string{
+				"A": {},
+				"B": {"A", "D"},
+				"C": {"B"},
+				"D": {"C"},
+				"E": {"D"},
+			},
+			wantErr: "operand dependency cycle found: B -> C -> D -> B",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opd, err := NewOperandDAG(namedOperands(tc.operands...), tc.required)
+			if err == nil {
+				_, err = opd.Order()
+			}
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tc.wantErr)
+			}
+			if err.Error() != tc.wantErr {
+				t.Errorf("unexpected error:\n\t(WNT) %q\n\t(GOT) %q", tc.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestDAGCycleErrorPath(t *testing.T) {
+	opd, err := NewOperandDAG(namedOperands("A", "B"), map[string][]string{"A": {"B"}, "B": {"A"}})
+	if err != nil {
+		t.Fatalf("unexpected error while creating OperandDAG: %v", err)
+	}
+	_, err = opd.Order()
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+	want := []string{"A", "B", "A"}
+	if strings.Join(cycleErr.Path, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected cycle path:\n\t(WNT) %v\n\t(GOT) %v", want, cycleErr.Path)
+	}
+}
+
+func TestDAGDeterministicOrder(t *testing.T) {
+	// The operands are given in reverse order. The order in each step must
+	// be by name regardless of the input order.
+	ops := namedOperands("F", "E", "D", "C", "B", "A")
+	required := map[string][]string{
+		"A": {}, "B": {}, "C": {},
+		"D": {"C", "A"}, "E": {"B"}, "F": {"B"},
+	}
+	want := `[
+  0: [ A B C ]
+  1: [ D E F ]
+]`
+	for i := 0; i < 10; i++ {
+		opd, err := NewOperandDAG(ops, required)
+		if err != nil {
+			t.Fatalf("unexpected error while creating OperandDAG: %v", err)
+		}
+		ordered, err := opd.Order()
+		if err != nil {
+			t.Fatalf("failed to order the operands: %v", err)
+		}
+		if ordered.String() != want {
+			t.Fatalf("unexpected results:\n\t(WNT) %q\n\t(GOT) %q", want, ordered)
+		}
+	}
+}
+
+// benchmarkGraph creates n operands in layers of width operands, each
+// requiring up to three operands of the previous layer.
+func benchmarkGraph(n, width int) ([]operand.Operand, map[string][]string) {
+	ops := []operand.Operand{}
+	required := map[string][]string{}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("op-%04d", i)
+		ops = append(ops, namedOperand{name: name})
+		required[name] = []string{}
+		layer := i / width
+		if layer == 0 {
+			continue
+		}
+		for j := 0; j < 3; j++ {
+			dep := (layer-1)*width + (i+j*7)%width
+			required[name] = append(required[name], fmt.Sprintf("op-%04d", dep))
+		}
+	}
+	return ops, required
+}
+
+func BenchmarkOrder(b *testing.B) {
+	for _, n := range []int{100, 500, 1000} {
+		ops, required := benchmarkGraph(n, 20)
+		b.Run(fmt.Sprintf("operands-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				opd, err := NewOperandDAG(ops, required)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := opd.Order(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
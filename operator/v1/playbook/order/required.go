@@ -35,3 +35,23 @@ func Required(operands []operand.Operand, operandRunCallName operand.OperandRunC
 	}
 	return requiredOperands, nil
 }
+
+// Without returns a copy of the RequiredOperands without the given operands.
+// The given operands are also removed from the required operands of the
+// remaining operands.
+func (r RequiredOperands) Without(excluded map[string]bool) RequiredOperands {
+	result := make(RequiredOperands, len(r))
+	for name, required := range r {
+		if excluded[name] {
+			continue
+		}
+		kept := []string{}
+		for _, req := range required {
+			if !excluded[req] {
+				kept = append(kept, req)
+			}
+		}
+		result[name] = kept
+	}
+	return result
+}
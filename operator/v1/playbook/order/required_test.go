@@ -0,0 +1,24 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredOperandsWithout(t *testing.T) {
+	required := RequiredOperands{
+		"A": {},
+		"B": {"A"},
+		"C": {"A", "B"},
+	}
+
+	got := required.Without(map[string]bool{"B": true})
+	assert.Equal(t, RequiredOperands{
+		"A": {},
+		"C": {"A"},
+	}, got)
+
+	// The original is not modified.
+	assert.Equal(t, []string{"A", "B"}, required["C"])
+}
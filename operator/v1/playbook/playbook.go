@@ -12,14 +12,24 @@ type Playbook struct {
 	dag      *dag.OperandDAG
 	order    *order.OperandOrder
 	blockers *order.BlockingOperands
+	required order.RequiredOperands
 }
 
+// NewPlaybook creates a Playbook of the given operands for the given run
+// call, based on the operands' requirements.
 func NewPlaybook(operands []operand.Operand, operandRunCallName operand.OperandRunCallName) (*Playbook, error) {
 	requiredOperands, err := order.Required(operands, operandRunCallName)
 	if err != nil {
 		return nil, err
 	}
 
+	return NewPlaybookFromRequired(operands, requiredOperands, operandRunCallName)
+}
+
+// NewPlaybookFromRequired creates a Playbook of the given operands for the
+// given run call, based on the given RequiredOperands. This can be used to
+// create a Playbook of a subset of operands with RequiredOperands.Without().
+func NewPlaybookFromRequired(operands []operand.Operand, requiredOperands order.RequiredOperands, operandRunCallName operand.OperandRunCallName) (*Playbook, error) {
 	operandDAG, err := dag.NewOperandDAG(operands, requiredOperands)
 	if err != nil {
 		return nil, err
@@ -48,6 +58,7 @@ func NewPlaybook(operands []operand.Operand, operandRunCallName operand.OperandR
 		dag:      operandDAG,
 		order:    &opOrder,
 		blockers: &blockers,
+		required: requiredOperands,
 	}, nil
 }
 
@@ -62,3 +73,37 @@ func (p *Playbook) Order() order.OperandOrder {
 func (p *Playbook) Blockers() order.BlockingOperands {
 	return *p.blockers
 }
+
+// Required returns the RequiredOperands the Playbook was created from.
+func (p *Playbook) Required() order.RequiredOperands {
+	return p.required
+}
+
+// WithOperands returns a copy of the Playbook in which the operands in the
+// order are replaced by the given operands with the same names. This allows
+// reusing a Playbook with new instances of the same operands. Operands that
+// aren't found in the given operands are kept.
+func (p *Playbook) WithOperands(operands []operand.Operand) *Playbook {
+	byName := make(map[string]operand.Operand, len(operands))
+	for _, op := range operands {
+		byName[op.Name()] = op
+	}
+
+	opOrder := make(order.OperandOrder, len(*p.order))
+	for i, step := range *p.order {
+		opOrder[i] = make([]operand.Operand, len(step))
+		for j, op := range step {
+			if replacement, ok := byName[op.Name()]; ok {
+				op = replacement
+			}
+			opOrder[i][j] = op
+		}
+	}
+
+	return &Playbook{
+		dag:      p.dag,
+		order:    &opOrder,
+		blockers: p.blockers,
+		required: p.required,
+	}
+}
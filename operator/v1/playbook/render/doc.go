@@ -0,0 +1,5 @@
+// Package render renders the operands graph of a playbook, with the
+// execution steps and the blocking operands, as Graphviz DOT, Mermaid or
+// JSON. The output is deterministic, which allows comparing it with golden
+// files in tests to review dependency changes.
+package render
@@ -0,0 +1,39 @@
+// Package golden provides a test helper to compare rendered output with
+// golden files. The caller decides when the golden files are updated, usually
+// with a flag of its own test package:
+//
+//	var update = flag.Bool("update-golden", false, "update the golden files")
+//
+//	golden.Assert(t, "testdata/ensure.dot", []byte(render.DOT(g)), *update)
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Assert compares the given output with the content of the golden file at
+// the given path. If update is true, the golden file is written with the
+// output instead.
+func Assert(t testing.TB, path string, got []byte, update bool) {
+	t.Helper()
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file %q: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %q: %v", path, err)
+	}
+	if string(want) != string(got) {
+		t.Errorf("output differs from golden file %q:\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ondat/operator-toolkit/operator/v1/playbook"
+)
+
+// Graph is a renderable representation of a playbook.
+type Graph struct {
+	// Name is the name of the graph, e.g. "ensure" or "cleanup".
+	Name string `json:"name"`
+	// Steps are the names of the operands in each execution step, sorted.
+	Steps [][]string `json:"steps"`
+	// Edges are the dependencies between the operands, sorted.
+	Edges []Edge `json:"edges"`
+	// Blockers are the names of the blocking operands, sorted.
+	Blockers []string `json:"blockers"`
+}
+
+// Edge is a dependency between two operands. The operand To requires the
+// operand From.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewGraph creates a Graph with the given name from a playbook.
+func NewGraph(name string, p *playbook.Playbook) *Graph {
+	g := &Graph{
+		Name:     name,
+		Steps:    [][]string{},
+		Edges:    []Edge{},
+		Blockers: []string{},
+	}
+
+	for _, step := range p.Order() {
+		names := []string{}
+		for _, op := range step {
+			names = append(names, op.Name())
+		}
+		sort.Strings(names)
+		g.Steps = append(g.Steps, names)
+	}
+
+	for name, required := range p.Required() {
+		for _, req := range required {
+			g.Edges = append(g.Edges, Edge{From: req, To: name})
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	for name, isBlocker := range p.Blockers() {
+		if isBlocker {
+			g.Blockers = append(g.Blockers, name)
+		}
+	}
+	sort.Strings(g.Blockers)
+
+	return g
+}
+
+// isBlocker checks if the given operand is a blocking operand.
+func (g *Graph) isBlocker(name string) bool {
+	i := sort.SearchStrings(g.Blockers, name)
+	return i < len(g.Blockers) && g.Blockers[i] == name
+}
+
+// DOT renders the graph in Graphviz DOT format. Each step is a cluster and
+// the blocking operands are drawn bold.
+func DOT(g *Graph) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.Name)
+	b.WriteString("  rankdir=LR;\n")
+	for i, step := range g.Steps {
+		fmt.Fprintf(&b, "  subgraph \"cluster_step_%d\" {\n", i)
+		fmt.Fprintf(&b, "    label=\"step %d\";\n", i)
+		for _, name := range step {
+			if g.isBlocker(name) {
+				fmt.Fprintf(&b, "    %q [style=bold];\n", name)
+			} else {
+				fmt.Fprintf(&b, "    %q;\n", name)
+			}
+		}
+		b.WriteString("  }\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Each step is a subgraph
+// and the blocking operands have the blocker class.
+func Mermaid(g *Graph) string {
+	// Mermaid node IDs can't contain all the characters of the operand
+	// names. Use generated IDs with the names as labels.
+	ids := map[string]string{}
+	id := func(name string) string {
+		if _, ok := ids[name]; !ok {
+			ids[name] = fmt.Sprintf("op%d", len(ids))
+		}
+		return ids[name]
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, step := range g.Steps {
+		fmt.Fprintf(&b, "  subgraph step%d [\"step %d\"]\n", i, i)
+		for _, name := range step {
+			fmt.Fprintf(&b, "    %s[%q]\n", id(name), name)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", id(e.From), id(e.To))
+	}
+	if len(g.Blockers) > 0 {
+		b.WriteString("  classDef blocker stroke-width:3px\n")
+		blockerIDs := []string{}
+		for _, name := range g.Blockers {
+			blockerIDs = append(blockerIDs, id(name))
+		}
+		fmt.Fprintf(&b, "  class %s blocker\n", strings.Join(blockerIDs, ","))
+	}
+	return b.String()
+}
+
+// JSON renders the graph as indented JSON.
+func JSON(g *Graph) ([]byte, error) {
+	out, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render graph %q as JSON: %w", g.Name, err)
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ondat/operator-toolkit/operator/v1/operand"
+	"github.com/ondat/operator-toolkit/operator/v1/operand/mocks"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook"
+	"github.com/ondat/operator-toolkit/operator/v1/playbook/render/golden"
+)
+
+var update = flag.Bool("update-golden", false, "update the golden files with the current output")
+
+func TestRender(t *testing.T) {
+	mctrl := gomock.NewController(t)
+	defer mctrl.Finish()
+
+	// A, B, C requires A, D requires C and E requires A and B. C is
+	// cleaned up after D.
+	deps := []struct {
+		name            string
+		requires        []string
+		cleanupRequires []string
+	}{
+		{"opA", []string{}, []string{}},
+		{"opB", []string{}, []string{}},
+		{"opC", []string{"opA"}, []string{"opD"}},
+		{"opD", []string{"opC"}, []string{}},
+		{"opE", []string{"opA", "opB"}, []string{}},
+	}
+	operands := []operand.Operand{}
+	for _, d := range deps {
+		m := mocks.NewMockOperand(mctrl)
+		m.EXPECT().Name().Return(d.name).AnyTimes()
+		m.EXPECT().Requires().Return(d.requires).AnyTimes()
+		m.EXPECT().CleanupRequires().Return(d.cleanupRequires).AnyTimes()
+		operands = append(operands, m)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		callName operand.OperandRunCallName
+	}{
+		{"ensure", operand.Ensure},
+		{"cleanup", operand.Cleanup},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := playbook.NewPlaybook(operands, tc.callName)
+			assert.Nil(t, err)
+
+			g := NewGraph(tc.name, p)
+
+			golden.Assert(t, filepath.Join("testdata", tc.name+".dot"), []byte(DOT(g)), *update)
+			golden.Assert(t, filepath.Join("testdata", tc.name+".mmd"), []byte(Mermaid(g)), *update)
+
+			out, err := JSON(g)
+			assert.Nil(t, err)
+			golden.Assert(t, filepath.Join("testdata", tc.name+".json"), out, *update)
+		})
+	}
+}
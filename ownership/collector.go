@@ -0,0 +1,239 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/ondat/operator-toolkit/constant"
+	"github.com/ondat/operator-toolkit/object"
+	"github.com/ondat/operator-toolkit/telemetry"
+	"github.com/ondat/operator-toolkit/telemetry/metrics"
+)
+
+const (
+	// Name of the instrumentation.
+	instrumentationName = constant.LibraryName + "/ownership"
+
+	// DefaultCollectionPeriod is the default period at which the garbage
+	// collection is executed.
+	DefaultCollectionPeriod = 5 * time.Minute
+)
+
+// GarbageCollector periodically deletes the objects of the given kinds with
+// ownership labels whose owner no longer exists, or was recreated with
+// another UID. It's a manager runnable that runs only on the leader.
+type GarbageCollector struct {
+	name   string
+	client client.Client
+	// reader is used to get the owners. It should read from the API server
+	// to avoid deleting objects because of a stale cache.
+	reader       client.Reader
+	kinds        []schema.GroupVersionKind
+	ownerKinds   map[schema.GroupKind]bool
+	period       time.Duration
+	startupDelay time.Duration
+	inst         *telemetry.Instrumentation
+}
+
+var _ manager.Runnable = &GarbageCollector{}
+var _ manager.LeaderElectionRunnable = &GarbageCollector{}
+
+// GarbageCollectorOption is used to configure GarbageCollector.
+type GarbageCollectorOption func(*GarbageCollector)
+
+// WithPeriod sets the period of the garbage collection.
+func WithPeriod(period time.Duration) GarbageCollectorOption {
+	return func(g *GarbageCollector) {
+		g.period = period
+	}
+}
+
+// WithStartupDelay sets a delay before the first garbage collection.
+func WithStartupDelay(delay time.Duration) GarbageCollectorOption {
+	return func(g *GarbageCollector) {
+		g.startupDelay = delay
+	}
+}
+
+// WithOwnerKinds limits the garbage collection to the objects owned by the
+// owners of the given kinds. By default, the objects of any owner kind are
+// collected.
+func WithOwnerKinds(kinds ...schema.GroupKind) GarbageCollectorOption {
+	return func(g *GarbageCollector) {
+		for _, gk := range kinds {
+			g.ownerKinds[gk] = true
+		}
+	}
+}
+
+// WithReader sets the reader used to get the owners. By default, the client
+// is used. AddToManager sets the API reader of the manager, if not set.
+func WithReader(reader client.Reader) GarbageCollectorOption {
+	return func(g *GarbageCollector) {
+		g.reader = reader
+	}
+}
+
+// WithInstrumentation configures the instrumentation of the
+// GarbageCollector.
+func WithInstrumentation(tp trace.TracerProvider, log logr.Logger) GarbageCollectorOption {
+	return func(g *GarbageCollector) {
+		if g.name != "" {
+			log = log.WithValues("garbage-collector", g.name)
+		}
+		g.inst = telemetry.NewInstrumentationWithProviders(instrumentationName, tp, log)
+	}
+}
+
+// NewGarbageCollector returns a GarbageCollector of the objects of the given
+// kinds, usually the managed kinds of an operator.
+func NewGarbageCollector(name string, c client.Client, kinds []schema.GroupVersionKind, opts ...GarbageCollectorOption) *GarbageCollector {
+	g := &GarbageCollector{
+		name:       name,
+		client:     c,
+		kinds:      kinds,
+		ownerKinds: map[schema.GroupKind]bool{},
+		period:     DefaultCollectionPeriod,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if g.inst == nil {
+		WithInstrumentation(nil, ctrl.Log)(g)
+	}
+
+	return g
+}
+
+// AddToManager adds the IndexOwnerUID index of the collected kinds and the
+// GarbageCollector to the manager.
+func (g *GarbageCollector) AddToManager(ctx context.Context, mgr manager.Manager) error {
+	if g.reader == nil {
+		g.reader = mgr.GetAPIReader()
+	}
+	if err := IndexOwned(ctx, mgr.GetFieldIndexer(), mgr.GetScheme(), g.kinds...); err != nil {
+		return err
+	}
+	return mgr.Add(g)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (g *GarbageCollector) NeedLeaderElection() bool { return true }
+
+// Start implements manager.Runnable. It runs the garbage collection at the
+// collection period until the context is done.
+func (g *GarbageCollector) Start(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(g.startupDelay):
+	}
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		// The errors are logged and recorded by Collect.
+		_ = g.Collect(ctx)
+	}, g.period)
+	return nil
+}
+
+// Collect runs the garbage collection once. It deletes the objects whose
+// owner no longer exists and returns the aggregated errors.
+func (g *GarbageCollector) Collect(ctx context.Context) error {
+	ctx, span, log := g.inst.Start(ctx, "Collect")
+	defer span.End()
+
+	reader := g.reader
+	if reader == nil {
+		reader = g.client
+	}
+
+	// Existence of the owners by UID, to get each owner only once.
+	owners := map[types.UID]bool{}
+	var errs []error
+
+	for _, gvk := range g.kinds {
+		list := object.NewObjectList(g.client.Scheme(), gvk)
+		if err := g.client.List(ctx, list, client.HasLabels{object.LabelOwnerUID}); err != nil {
+			span.RecordError(err)
+			log.Error(err, "failed to list owned objects", "kind", gvk)
+			errs = append(errs, fmt.Errorf("failed to list %s: %w", gvk, err))
+			continue
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to extract %s list: %w", gvk, err))
+			continue
+		}
+
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			owner, ok := object.OwnerFromLabels(obj)
+			if !ok || (len(g.ownerKinds) > 0 && !g.ownerKinds[owner.GroupVersionKind.GroupKind()]) {
+				continue
+			}
+
+			exists, found := owners[owner.UID]
+			if !found {
+				exists, err = ownerExists(ctx, reader, owner)
+				if err != nil {
+					span.RecordError(err)
+					log.Error(err, "failed to get owner", "owner", owner.Key(), "kind", owner.GroupVersionKind)
+					errs = append(errs, err)
+					continue
+				}
+				owners[owner.UID] = exists
+			}
+			if exists {
+				continue
+			}
+
+			log.Info("deleting orphan object", "kind", gvk.Kind, "object", client.ObjectKeyFromObject(obj), "owner", owner.Key())
+			uid := obj.GetUID()
+			err := g.client.Delete(ctx, obj, client.Preconditions{UID: &uid}, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if client.IgnoreNotFound(err) != nil {
+				span.RecordError(err)
+				metrics.GarbageCollectionDeletions.WithLabelValues(g.name, metrics.ResultError).Inc()
+				log.Error(err, "failed to delete orphan object", "kind", gvk.Kind, "object", client.ObjectKeyFromObject(obj))
+				errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", gvk.Kind, client.ObjectKeyFromObject(obj), err))
+				continue
+			}
+			metrics.GarbageCollectionDeletions.WithLabelValues(g.name, metrics.ResultSuccess).Inc()
+		}
+	}
+
+	return kerrors.NewAggregate(errs)
+}
+
+// ownerExists tells if the owner exists with the same UID.
+func ownerExists(ctx context.Context, reader client.Reader, owner object.Owner) (bool, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(owner.GroupVersionKind)
+	if err := reader.Get(ctx, owner.Key(), obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get owner %s %s: %w", owner.GroupVersionKind.Kind, owner.Key(), err)
+	}
+	return obj.GetUID() == owner.UID, nil
+}
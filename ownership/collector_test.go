@@ -0,0 +1,88 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/ondat/operator-toolkit/object"
+)
+
+func TestGarbageCollectorCollect(t *testing.T) {
+	configMapGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	clusterRoleGVK := rbacv1.SchemeGroupVersion.WithKind("ClusterRole")
+
+	parent := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "parent", Namespace: "default", UID: "parent-uid"},
+	}
+
+	owned := func(name string, owner object.Owner) *rbacv1.ClusterRole {
+		obj := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")}}
+		assert.Nil(t, object.SetOwnershipLabels(obj, owner))
+		return obj
+	}
+
+	parentOwner := object.Owner{GroupVersionKind: configMapGVK, Namespace: "default", Name: "parent", UID: "parent-uid"}
+	missingOwner := object.Owner{GroupVersionKind: configMapGVK, Namespace: "default", Name: "missing", UID: "missing-uid"}
+	recreatedOwner := object.Owner{GroupVersionKind: configMapGVK, Namespace: "default", Name: "parent", UID: "old-uid"}
+	otherKindOwner := object.Owner{GroupVersionKind: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, Name: "widget", UID: "widget-uid"}
+
+	cases := []struct {
+		name        string
+		objects     []client.Object
+		opts        []GarbageCollectorOption
+		wantExist   []string
+		wantDeleted []string
+	}{
+		{
+			name: "orphans deleted",
+			objects: []client.Object{
+				owned("kept", parentOwner),
+				owned("missing-owner", missingOwner),
+				owned("recreated-owner", recreatedOwner),
+				&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled"}},
+			},
+			wantExist:   []string{"kept", "unlabelled"},
+			wantDeleted: []string{"missing-owner", "recreated-owner"},
+		},
+		{
+			name: "owner kinds",
+			objects: []client.Object{
+				owned("missing-owner", missingOwner),
+				owned("other-kind", otherKindOwner),
+			},
+			opts:        []GarbageCollectorOption{WithOwnerKinds(configMapGVK.GroupKind())},
+			wantExist:   []string{"other-kind"},
+			wantDeleted: []string{"missing-owner"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			objects := append([]client.Object{parent.DeepCopy()}, tc.objects...)
+			cli := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+
+			gc := NewGarbageCollector("test", cli, []schema.GroupVersionKind{clusterRoleGVK}, tc.opts...)
+			assert.Nil(t, gc.Collect(context.TODO()))
+
+			for _, name := range tc.wantExist {
+				assert.Nil(t, cli.Get(context.TODO(), client.ObjectKey{Name: name}, &rbacv1.ClusterRole{}), name)
+			}
+			for _, name := range tc.wantDeleted {
+				err := cli.Get(context.TODO(), client.ObjectKey{Name: name}, &rbacv1.ClusterRole{})
+				assert.True(t, apierrors.IsNotFound(err), name)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Package ownership tracks the child objects that carry the ownership labels
+// and annotations of their parent object, see object.SetOwnershipLabels. The
+// child objects are selected and indexed by the owner UID label. Unlike owner
+// references, the ownership labels work for cluster-scoped children of
+// namespaced parents and for children in another namespace, but they aren't
+// handled by the kubernetes garbage collector. The package provides an index
+// of the child objects by owner and a GarbageCollector that deletes the child
+// objects whose owner no longer exists.
+package ownership
@@ -0,0 +1,45 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// IndexOwnerUID is the name of the field index of the objects by the UID of
+// their owner, from their ownership labels.
+const IndexOwnerUID = "metadata.labels.ownerUID"
+
+// IndexOwned adds the IndexOwnerUID index of the objects of the given kinds to
+// the field indexer, usually the field indexer of the manager. It must be
+// called before the manager is started.
+func IndexOwned(ctx context.Context, indexer client.FieldIndexer, scheme *runtime.Scheme, kinds ...schema.GroupVersionKind) error {
+	for _, gvk := range kinds {
+		if err := indexer.IndexField(ctx, object.NewObject(scheme, gvk), IndexOwnerUID, ownerUID); err != nil {
+			return fmt.Errorf("failed to index %s by owner: %w", gvk, err)
+		}
+	}
+	return nil
+}
+
+// ownerUID returns the UID of the owner of the object from its ownership
+// labels.
+func ownerUID(obj client.Object) []string {
+	owner, ok := object.OwnerFromLabels(obj)
+	if !ok {
+		return nil
+	}
+	return []string{string(owner.UID)}
+}
+
+// OwnedBy returns a list option that selects the objects owned by the owner
+// with the given UID, using the IndexOwnerUID index.
+func OwnedBy(uid types.UID) client.MatchingFields {
+	return client.MatchingFields{IndexOwnerUID: string(uid)}
+}
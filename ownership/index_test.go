@@ -0,0 +1,56 @@
+package ownership
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ondat/operator-toolkit/object"
+)
+
+// fakeIndexer records the indexed objects and extractors.
+type fakeIndexer struct {
+	objects    []client.Object
+	extractors map[string]client.IndexerFunc
+}
+
+func (f *fakeIndexer) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	f.objects = append(f.objects, obj)
+	f.extractors[field] = extractValue
+	return nil
+}
+
+func TestIndexOwned(t *testing.T) {
+	indexer := &fakeIndexer{extractors: map[string]client.IndexerFunc{}}
+	kinds := []schema.GroupVersionKind{
+		rbacv1.SchemeGroupVersion.WithKind("ClusterRole"),
+		{Group: "example.com", Version: "v1", Kind: "Widget"},
+	}
+	assert.Nil(t, IndexOwned(context.TODO(), indexer, scheme.Scheme, kinds...))
+	assert.Len(t, indexer.objects, 2)
+	assert.IsType(t, &rbacv1.ClusterRole{}, indexer.objects[0])
+
+	extract := indexer.extractors[IndexOwnerUID]
+	assert.NotNil(t, extract)
+
+	obj := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	assert.Empty(t, extract(obj))
+
+	owner := object.Owner{
+		GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		Namespace:        "default",
+		Name:             "parent",
+		UID:              "parent-uid",
+	}
+	assert.Nil(t, object.SetOwnershipLabels(obj, owner))
+	assert.Equal(t, []string{"parent-uid"}, extract(obj))
+
+	assert.Equal(t, client.MatchingFields{IndexOwnerUID: "parent-uid"}, OwnedBy("parent-uid"))
+}
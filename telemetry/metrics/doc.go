@@ -0,0 +1,5 @@
+// Package metrics provides the prometheus metrics of the toolkit reconcilers
+// and operand executions. The metrics are registered with the
+// controller-runtime metrics registry and are served by the metrics server of
+// the manager along with the controller-runtime metrics.
+package metrics
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// namespace is the prefix of all the metrics.
+const namespace = "operator_toolkit"
+
+// Reconcile results.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultRequeue = "requeue"
+)
+
+// Operand calls.
+const (
+	CallEnsure = "ensure"
+	CallDelete = "delete"
+)
+
+// OperandOther is the operand label of the operand metrics of the operands
+// executed once the maximum number of operand labels of an executor is
+// reached, see executor.WithMaxOperandLabels.
+const OperandOther = "other"
+
+var (
+	// ReconcileTotal counts the reconciliations by reconciler type, name and
+	// result.
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_total",
+		Help:      "Total number of reconciliations per reconciler and result.",
+	}, []string{"type", "reconciler", "result"})
+
+	// ReconcileDuration is the duration of the reconciliations by reconciler
+	// type and name.
+	ReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of the reconciliations per reconciler.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"type", "reconciler"})
+
+	// OperandDuration is the duration of the operand executions by
+	// reconciler, operand and call. The operand label is the operand name,
+	// which varies per parent object for the operands generated from the
+	// parent. The number of operand labels of an executor is bounded, the
+	// operands past the limit share the OperandOther label.
+	OperandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "operand_duration_seconds",
+		Help:      "Duration of the operand ensure and delete executions.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"reconciler", "operand", "call"})
+
+	// OperandFailures counts the failed operand executions by reconciler,
+	// operand, call and error class.
+	OperandFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operand_failures_total",
+		Help:      "Total number of failed operand executions per reconciler, operand, call and error class.",
+	}, []string{"reconciler", "operand", "call", "class"})
+
+	// OperandNotReady counts the operand executions that failed the
+	// readiness check, by reconciler and operand.
+	OperandNotReady = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "operand_not_ready_total",
+		Help:      "Total number of operand executions that failed the readiness check.",
+	}, []string{"reconciler", "operand"})
+
+	// GarbageCollectionDeletions counts the objects deleted by the garbage
+	// collectors, by reconciler and result.
+	GarbageCollectionDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "garbage_collection_deletions_total",
+		Help:      "Total number of objects deleted by garbage collection per reconciler and result.",
+	}, []string{"reconciler", "result"})
+
+	// ResyncTotal counts the runs of the sync functions, by reconciler.
+	ResyncTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "resync_total",
+		Help:      "Total number of sync function runs per reconciler.",
+	}, []string{"reconciler"})
+
+	// ActionRetries counts the retries of the stateless actions, by
+	// reconciler.
+	ActionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "action_retries_total",
+		Help:      "Total number of stateless action retries per reconciler.",
+	}, []string{"reconciler"})
+
+	// Panics counts the recovered panics, by component and reconciler name.
+	// The panics of an operand are also counted by OperandFailures, with
+	// the operand and the "panic" class.
+	Panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "panics_total",
+		Help:      "Total number of recovered panics per component and reconciler.",
+	}, []string{"component", "name"})
+)
+
+// Components of the recovered panics.
+const (
+	ComponentOperand    = "operand"
+	ComponentSyncFunc   = "syncfunc"
+	ComponentAction     = "action"
+	ComponentReconciler = "reconciler"
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ReconcileTotal,
+		ReconcileDuration,
+		OperandDuration,
+		OperandFailures,
+		OperandNotReady,
+		GarbageCollectionDeletions,
+		ResyncTotal,
+		ActionRetries,
+		Panics,
+	)
+}
+
+// ObserveReconcile records the result and the duration of a reconciliation.
+// The start time is the start time of the reconcile function. It's meant to
+// be deferred at the start of the reconcile function, like
+// controller.LogReconcileFinish.
+func ObserveReconcile(reconcilerType, name string, start time.Time, result *ctrl.Result, e *error) {
+	ReconcileDuration.WithLabelValues(reconcilerType, name).Observe(time.Since(start).Seconds())
+	ReconcileTotal.WithLabelValues(reconcilerType, name, reconcileResult(result, e)).Inc()
+}
+
+// reconcileResult returns the result label of a reconciliation.
+func reconcileResult(result *ctrl.Result, e *error) string {
+	switch {
+	case e != nil && *e != nil:
+		return ResultError
+	case result != nil && (result.Requeue || result.RequeueAfter > 0):
+		return ResultRequeue
+	}
+	return ResultSuccess
+}
+
+// ErrorClass returns a low cardinality class of an error, to be used as a
+// metric label. The API errors are classified by their reason.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	if reason := apierrors.ReasonForError(err); reason != "" {
+		return string(reason)
+	}
+	return "other"
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestObserveReconcile(t *testing.T) {
+	errFoo := errors.New("foo")
+	var noErr error
+
+	cases := []struct {
+		name       string
+		result     ctrl.Result
+		err        *error
+		wantResult string
+	}{
+		{name: "success", err: &noErr, wantResult: ResultSuccess},
+		{name: "requeue", result: ctrl.Result{RequeueAfter: time.Second}, err: &noErr, wantResult: ResultRequeue},
+		{name: "error", result: ctrl.Result{Requeue: true}, err: &errFoo, wantResult: ResultError},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := "test-" + tc.name
+			ObserveReconcile("test", reconciler, time.Now(), &tc.result, tc.err)
+			assert.Equal(t, float64(1), testutil.ToFloat64(ReconcileTotal.WithLabelValues("test", reconciler, tc.wantResult)))
+		})
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{err: nil, want: ""},
+		{err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), want: "timeout"},
+		{err: context.Canceled, want: "canceled"},
+		{err: apierrors.NewNotFound(gr, "foo"), want: "NotFound"},
+		{err: apierrors.NewConflict(gr, "foo", errors.New("conflict")), want: "Conflict"},
+		{err: errors.New("foo"), want: "other"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, ErrorClass(tc.err), fmt.Sprint(tc.err))
+	}
+}
@@ -38,6 +38,8 @@ type GameStatus struct {
 	// Important: Run "make" to regenerate code after modifying this file
 
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -52,6 +54,16 @@ type Game struct {
 	Status GameStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the status conditions of the Game.
+func (g *Game) GetConditions() []metav1.Condition {
+	return g.Status.Conditions
+}
+
+// SetConditions sets the status conditions of the Game.
+func (g *Game) SetConditions(conditions []metav1.Condition) {
+	g.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // GameList contains a list of Game